@@ -1,15 +1,39 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/bundle"
 	"github.com/avinor/tau/pkg/config/loader"
 	"github.com/avinor/tau/pkg/helpers/paths"
 	"github.com/avinor/tau/pkg/helpers/ui"
 	"github.com/avinor/tau/pkg/shell"
 	"github.com/avinor/tau/pkg/shell/processors"
+	"github.com/avinor/tau/pkg/terraform/def"
+)
+
+// applyDecision is the answer when asking to apply a module
+type applyDecision string
+
+const (
+	applyDecisionApply     applyDecision = "apply"
+	applyDecisionSkip      applyDecision = "skip"
+	applyDecisionAbort     applyDecision = "abort"
+	applyDecisionNoChanges applyDecision = "no changes"
+
+	// applyDecisionAborted is used when run was aborted before asking to apply module
+	applyDecisionAborted applyDecision = "aborted"
 )
 
 type applyCmd struct {
@@ -17,13 +41,50 @@ type applyCmd struct {
 
 	autoApprove bool
 	deletePlan  bool
+	bundle      string
+
+	// interactive is set if user should approve each module before it is applied
+	interactive bool
+
+	// decisions made for each file and if run was aborted, guarded by lock. askLock makes
+	// sure only one module is asking for approval at a time
+	decisions map[*loader.ParsedFile]applyDecision
+	aborted   bool
+	lock      sync.Mutex
+	askLock   sync.Mutex
 }
 
 var (
+	// applyAborted is returned when user aborts the run
+	applyAborted = errors.Errorf("apply aborted by user")
+
+	// stalePlan is returned when configuration, module or inputs changed after plan was created
+	stalePlan = errors.Errorf("plan is stale, run tau plan again")
+
+	// bundleNoSelectedFiles is returned when none of the deployments in bundle are selected
+	bundleNoSelectedFiles = errors.Errorf("none of the deployments in bundle are selected")
+
 	// applyLong is long description of apply command
 	applyLong = templates.LongDesc(`Apply an execution plan where its possible. It will
 		loop through all plans generated from plan command and execute them. It will only
 		execute for those modules that successfully generated a plan.
+
+		Without --auto-approve, and when running in a terminal, tau shows a summary of the
+		plan for each module, with resources that are destroyed or replaced highlighted.
+		It then asks whether to apply the module, skip it or abort the run. If no plan
+		exists for the module it creates one first. The decisions are printed at the end.
+
+		When not running in a terminal, for instance in a CI pipeline, existing plans are
+		applied without asking.
+
+		Tau refuses to apply a plan if the tau file, any of the files merged into it, the
+		module, the input variables or the dependency outputs have changed since the plan
+		was created. Run plan again to create a new plan.
+
+		Use --bundle to apply plans in a bundle written by tau plan --bundle. All files in
+		bundle are verified against its manifest before it is extracted into .tau. Only
+		deployments in bundle are applied, using the module, input variables and plan from
+		bundle, so modules are not downloaded and dependencies are not resolved again.
 		`)
 
 	// applyExample is examples for apply command
@@ -36,12 +97,17 @@ var (
 
 		# Apply a single module and auto approve
 		tau apply -f module.hcl --no-input
+
+		# Apply plans in bundle created by tau plan --bundle plan.tar.gz
+		tau apply --bundle plan.tar.gz --auto-approve
 	`)
 )
 
 // newApplyCmd creates a new apply command
 func newApplyCmd() *cobra.Command {
-	ac := &applyCmd{}
+	ac := &applyCmd{
+		decisions: map[*loader.ParsedFile]applyDecision{},
+	}
 
 	applyCmd := &cobra.Command{
 		Use:                   "apply [-f SORUCE]",
@@ -64,8 +130,11 @@ func newApplyCmd() *cobra.Command {
 	f := applyCmd.Flags()
 	f.BoolVar(&ac.autoApprove, "auto-approve", false, "auto approve deployment")
 	f.BoolVar(&ac.deletePlan, "delete-plan", true, "delete terraform plan on success")
+	f.StringVar(&ac.bundle, "bundle", "", "apply plans in bundle file created by plan")
 
 	ac.addMetaFlags(applyCmd)
+	ac.addSelectionFlags(applyCmd)
+	ac.addWalkFlags(applyCmd)
 
 	return applyCmd
 }
@@ -84,6 +153,13 @@ func (ac *applyCmd) run(args []string) error {
 		}
 	}
 
+	if ac.bundle != "" {
+		files, err = ac.extractBundle(files)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Check if any plans exist, if not then run plan first
 	noPlansExists := true
 	for _, file := range files {
@@ -97,9 +173,15 @@ func (ac *applyCmd) run(args []string) error {
 		ui.Header("Found tau.plan files, only applying valid plans...")
 	}
 
-	if err := files.Walk(func(file *loader.ParsedFile) error {
+	ac.interactive = !ac.autoApprove && isatty.IsTerminal(os.Stdin.Fd())
+
+	err = ac.walk(files, func(file *loader.ParsedFile) error {
 		return ac.runFile(file, !noPlansExists)
-	}); err != nil {
+	})
+
+	ac.printDecisions(files)
+
+	if err != nil {
 		return err
 	}
 
@@ -109,19 +191,29 @@ func (ac *applyCmd) run(args []string) error {
 }
 
 func (ac *applyCmd) runFile(file *loader.ParsedFile, onlyPlans bool) error {
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
+
+	if ac.isAborted() {
+		return ac.skipAborted(file)
+	}
 
 	// Running prepare hook
 
-	ui.Header("Executing prepare hooks...")
+	file.Log.Header("Executing prepare hooks...")
 
-	if err := ac.Runner.Run(file, "prepare", "apply"); err != nil {
+	if err := ac.runHooks(file, "prepare", "apply"); err != nil {
 		return err
 	}
 
-	ac.autoInit(file)
+	if err := ac.autoInit(file); err != nil {
+		return err
+	}
 
-	// Resolving dependencies
+	// Resolving dependencies. Input variables are only written when missing, an existing plan
+	// is verified against current dependency outputs without changing its input variables.
+	// Outputs are also resolved before creating a plan, so they are part of its fingerprint
+
+	planFileExists := paths.IsFile(file.PlanFile())
 
 	if !paths.IsFile(file.VariableFile()) {
 		success, err := ac.resolveDependencies(file)
@@ -130,30 +222,69 @@ func (ac *applyCmd) runFile(file *loader.ParsedFile, onlyPlans bool) error {
 		}
 
 		if !success {
-			return nil
+			return ac.skipUnresolved(file)
+		}
+	} else if ac.bundle == "" && (planFileExists || ac.interactive) {
+		success, err := ac.resolveDependencyValues(file)
+		if err != nil {
+			return err
 		}
-	}
 
-	planFileExists := paths.IsFile(file.PlanFile())
+		if !success {
+			return ac.skipUnresolved(file)
+		}
+	}
 
 	if !planFileExists && onlyPlans {
-		ui.Warn("No plan exists")
+		file.Log.Warn("No plan exists")
 		return nil
 	}
 
+	// Plans in bundle have been verified against the manifest of bundle
+	if planFileExists && ac.bundle == "" {
+		if err := ac.verifyPlan(file); err != nil {
+			return err
+		}
+	}
+
 	// Executing terraform command
 
-	ui.NewLine()
-	ui.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
-	ui.NewLine()
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
 
 	options := &shell.Options{
 		WorkingDirectory: file.ModuleDir(),
-		Stdout:           shell.Processors(processors.NewUI(ui.Info)),
-		Stderr:           shell.Processors(processors.NewUI(ui.Error)),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
 		Env:              file.Env,
 	}
 
+	if ac.interactive {
+		if !planFileExists {
+			if err := ac.createPlan(file, options); err != nil {
+				return err
+			}
+
+			planFileExists = true
+		}
+
+		decision, err := ac.approve(file, options)
+		if err != nil {
+			return err
+		}
+
+		switch decision {
+		case applyDecisionSkip:
+			ac.report.skip(file, "skipped by user")
+			return fileSkipped
+		case applyDecisionAbort:
+			return applyAborted
+		case applyDecisionAborted:
+			return ac.skipAborted(file)
+		}
+	}
+
 	extraArgs := getExtraArgs(ac.Engine.Compatibility.GetInvalidArgs("apply")...)
 	extraArgs = append(extraArgs, "-input=false")
 
@@ -165,21 +296,265 @@ func (ac *applyCmd) runFile(file *loader.ParsedFile, onlyPlans bool) error {
 		extraArgs = append(extraArgs, file.PlanFile())
 	}
 
-	if err := ac.Engine.Executor.Execute(options, "apply", extraArgs...); err != nil {
+	if err := ac.execute(file, options, "apply", extraArgs...); err != nil {
 		return err
 	}
 
 	if ac.deletePlan {
 		paths.Remove(file.PlanFile())
+		paths.Remove(file.FingerprintFile())
 	}
 
 	// Executing finish hook
 
-	ui.Header("Executing finish hooks...")
+	file.Log.Header("Executing finish hooks...")
 
-	if err := ac.Runner.Run(file, "finish", "apply"); err != nil {
+	if err := ac.runHooks(file, "finish", "apply"); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// extractBundle verifies and extracts bundle into tau directory, and returns the files that
+// are in bundle. Deployments in bundle that are not selected are not applied
+func (ac *applyCmd) extractBundle(files loader.ParsedFileCollection) (loader.ParsedFileCollection, error) {
+	ui.Header("Extracting bundle...")
+
+	manifest, err := bundle.Extract(paths.Abs(workingDir, ac.bundle), ac.TauDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ui.Info("- Verified %v deployment(s) in bundle created %s", len(manifest.Deployments),
+		manifest.Created.Local().Format(time.RFC3339))
+
+	selected := map[string]*loader.ParsedFile{}
+	for _, file := range files {
+		selected[file.Name] = file
+	}
+
+	bundled := loader.ParsedFileCollection{}
+	for _, deployment := range manifest.Deployments {
+		file, ok := selected[deployment.Name]
+		if !ok {
+			ui.Warn("- %s is in bundle but not selected, it is not applied", deployment.Name)
+			continue
+		}
+
+		bundled = append(bundled, file)
+	}
+
+	if len(bundled) == 0 {
+		return nil, bundleNoSelectedFiles
+	}
+
+	return bundled, nil
+}
+
+// createPlan creates a plan for file, so it can be shown before asking to apply it
+func (ac *applyCmd) createPlan(file *loader.ParsedFile, options *shell.Options) error {
+	file.Log.Header("Creating plan...")
+
+	extraArgs := getExtraArgs(ac.Engine.Compatibility.GetInvalidArgs("plan")...)
+	extraArgs = append(extraArgs, "-input=false", fmt.Sprintf("-out=%s", file.PlanFile()))
+
+	if file.ShouldDelete {
+		extraArgs = append(extraArgs, "-destroy")
+	}
+
+	if err := ac.Engine.Executor.Execute(options, "plan", extraArgs...); err != nil {
+		return err
+	}
+
+	if err := file.WriteFingerprint(); err != nil {
+		file.Log.Warn("Could not write fingerprint of plan: %s", err)
+	}
+
+	return nil
+}
+
+// verifyPlan checks that plan for file was created from current configuration, module,
+// variable values, input variables and dependency outputs. Dependencies must have been
+// resolved before calling verifyPlan. Plans without a fingerprint, created by earlier
+// versions of tau, cannot be checked and are applied
+func (ac *applyCmd) verifyPlan(file *loader.ParsedFile) error {
+	planned, err := file.ReadFingerprint()
+	if os.IsNotExist(err) {
+		file.Log.Warn("Plan has no fingerprint, cannot check if it is stale")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	current, err := file.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	changes := planned.Changes(current)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	file.Log.Error("Plan for %s is stale, changes since plan was created:", file.Name)
+
+	for _, change := range changes {
+		file.Log.Error("- %s", change)
+	}
+
+	return stalePlan
+}
+
+// approve shows summary of plan for file and asks user whether to apply it, skip it or abort
+// the run. Plans without changes are applied without asking. Only one file asks at a time.
+func (ac *applyCmd) approve(file *loader.ParsedFile, options *shell.Options) (applyDecision, error) {
+	summary, err := ac.Engine.Executor.SummarizePlan(&shell.Options{
+		WorkingDirectory: options.WorkingDirectory,
+		Stderr:           options.Stderr,
+		Env:              options.Env,
+	}, file.PlanFile())
+	if err != nil {
+		return "", err
+	}
+
+	if !summary.HasChanges() {
+		file.Log.Info("No changes in plan for %s", file.Name)
+		ac.setDecision(file, applyDecisionNoChanges)
+		return applyDecisionApply, nil
+	}
+
+	ac.askLock.Lock()
+	defer ac.askLock.Unlock()
+
+	// another module may have aborted while waiting to ask
+	if ac.isAborted() {
+		return applyDecisionAborted, nil
+	}
+
+	printPlanPreview(file, summary)
+
+	query := fmt.Sprintf("Apply %s? [y]es, [s]kip or [a]bort:", file.Name)
+
+	for {
+		answer, err := ui.Ask(query)
+		if err == io.EOF {
+			answer, err = "abort", nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		decision, ok := parseApplyDecision(answer)
+		if !ok {
+			file.Log.Warn("Invalid answer %q", answer)
+			continue
+		}
+
+		file.Log.Info("Decision for %s: %s", file.Name, decision)
+		ac.setDecision(file, decision)
+
+		return decision, nil
+	}
+}
+
+// setDecision records decision for file, and marks run as aborted if decision is abort
+func (ac *applyCmd) setDecision(file *loader.ParsedFile, decision applyDecision) {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+
+	ac.decisions[file] = decision
+
+	if decision == applyDecisionAbort {
+		ac.aborted = true
+	}
+}
+
+// skipAborted skips file because run has been aborted
+func (ac *applyCmd) skipAborted(file *loader.ParsedFile) error {
+	file.Log.Warn("Run aborted, not applying %s", file.Name)
+	ac.report.skip(file, "run aborted by user")
+
+	return nil
+}
+
+// skipUnresolved skips file because its dependencies could not be resolved, so files that
+// depend on it are skipped as well
+func (ac *applyCmd) skipUnresolved(file *loader.ParsedFile) error {
+	ac.report.skip(file, "dependencies could not be resolved")
+
+	return fileSkipped
+}
+
+// isAborted returns true if user has aborted the run
+func (ac *applyCmd) isAborted() bool {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+
+	return ac.aborted
+}
+
+// printDecisions prints the decision made for each file, in same order as files were loaded
+func (ac *applyCmd) printDecisions(files loader.ParsedFileCollection) {
+	if len(ac.decisions) == 0 {
+		return
+	}
+
+	ui.Separator("Apply decisions")
+
+	for _, file := range files {
+		decision, ok := ac.decisions[file]
+		if !ok {
+			continue
+		}
+
+		line := fmt.Sprintf("- %s: %s", file.Name, decision)
+
+		switch decision {
+		case applyDecisionSkip:
+			line = color.YellowString(line)
+		case applyDecisionAbort:
+			line = color.RedString(line)
+		}
+
+		ui.Info("%s", line)
+	}
+}
+
+// printPlanPreview prints number of changes in plan and each resource changed. Resources
+// that are destroyed or replaced are highlighted
+func printPlanPreview(file *loader.ParsedFile, summary *def.PlanSummary) {
+	file.Log.Header("Plan summary")
+	file.Log.Info("%v to add, %v to change, %v to replace, %v to destroy",
+		summary.Add, summary.Change, summary.Replace, summary.Destroy)
+	file.Log.NewLine()
+
+	for _, resource := range summary.Resources {
+		line := fmt.Sprintf("  %-8s %s", resource.Action, resource.Address)
+
+		if resource.Action == "replace" || resource.Action == "destroy" {
+			line = color.New(color.FgRed, color.Bold).Sprint(line)
+		}
+
+		file.Log.Info("%s", line)
+	}
+
+	file.Log.NewLine()
+}
+
+// parseApplyDecision parses the answer when asking to apply a module
+func parseApplyDecision(answer string) (applyDecision, bool) {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "apply":
+		return applyDecisionApply, true
+	case "s", "skip", "n", "no":
+		return applyDecisionSkip, true
+	case "a", "abort":
+		return applyDecisionAbort, true
+	}
+
+	return "", false
+}
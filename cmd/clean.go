@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/paths"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+type cleanCmd struct {
+	meta
+
+	orphaned bool
+	cache    bool
+	all      bool
+	dryRun   bool
+	force    bool
+}
+
+var (
+	// cleanLong is long description of clean command
+	cleanLong = templates.LongDesc(`Remove files generated by tau. By default it removes the
+		working directory in .tau for selected files, including downloaded module, resolved
+		dependencies, input variables and plans. Next command will initialize them again.
+
+		Use --orphaned to remove directories in .tau where the tau file no longer exists,
+		and --cache to remove plugin cache and downloaded hook scripts. To remove everything
+		tau has generated use --all.
+
+		Selected files are locked while their directories are removed, so clean waits for, or
+		fails on, other tau runs using the same files. With --all and --orphaned clean refuses
+		to run while any file is locked, unless --force is set.
+
+		Remote state is never changed, only local files are removed.
+		`)
+
+	// cleanExample is examples for clean command
+	cleanExample = templates.Examples(`
+		# Remove generated files for all files in current folder
+		tau clean
+
+		# Remove generated files for a single file
+		tau clean -f module.hcl
+
+		# List directories of files that have been deleted, without removing them
+		tau clean --orphaned --dry-run
+
+		# Remove plugin and script cache
+		tau clean --cache
+	`)
+)
+
+// newCleanCmd creates a new clean command
+func newCleanCmd() *cobra.Command {
+	cc := &cleanCmd{
+		meta: meta{
+			noTerraform: true,
+		},
+	}
+
+	cleanCmd := &cobra.Command{
+		Use:                   "clean [-f SOURCE]",
+		Short:                 "Remove files generated by tau",
+		Long:                  cleanLong,
+		Example:               cleanExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cc.meta.init(args); err != nil {
+				return err
+			}
+
+			return cc.run(args)
+		},
+	}
+
+	f := cleanCmd.Flags()
+	f.BoolVar(&cc.orphaned, "orphaned", false, "remove directories of files that no longer exist")
+	f.BoolVar(&cc.cache, "cache", false, "remove plugin cache and downloaded hook scripts")
+	f.BoolVar(&cc.all, "all", false, "remove all files generated by tau, for all files")
+	f.BoolVar(&cc.dryRun, "dry-run", false, "list files that would be removed without removing them")
+	f.BoolVar(&cc.force, "force", false, "remove files with --all or --orphaned even if other tau runs hold locks")
+	f.DurationVar(&cc.lockTimeout, "lock-timeout", 0, "time to wait for other tau runs to release lock on a module")
+
+	cc.addMetaFlags(cleanCmd)
+	cc.addSelectionFlags(cleanCmd)
+
+	return cleanCmd
+}
+
+func (cc *cleanCmd) run(args []string) error {
+	targets, unlock, err := cc.targets()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if cc.dryRun {
+		ui.Header("Files that would be removed...")
+	} else {
+		ui.Header("Removing files...")
+	}
+
+	if len(targets) == 0 {
+		ui.Info("- Nothing to remove")
+	}
+
+	for _, target := range targets {
+		ui.Info("- %s", relativeToWorkingDir(target))
+
+		if !cc.dryRun {
+			paths.Remove(target)
+		}
+	}
+
+	ui.NewLine()
+
+	if !cc.dryRun && len(targets) > 0 {
+		ui.Info(color.New(color.FgGreen, color.Bold).Sprintf("Removed %v folder(s).", len(targets)))
+		ui.NewLine()
+	}
+
+	return nil
+}
+
+// targets returns all directories that should be removed depending on flags. When removing
+// directories of selected files it takes the lock on each file, returned function releases
+// the locks and has to be called when done removing files.
+func (cc *cleanCmd) targets() ([]string, func(), error) {
+	noUnlock := func() {}
+
+	if !cc.all && !cc.orphaned && !cc.cache {
+		return cc.fileTargets()
+	}
+
+	if (cc.all || cc.orphaned) && !cc.dryRun && !cc.force {
+		if err := checkNoLocks(cc.TauDir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cc.all {
+		return existingDirs(cc.TauDir, cc.CacheDir), noUnlock, nil
+	}
+
+	targets := []string{}
+
+	if cc.orphaned {
+		orphaned, err := cc.orphanedTargets()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		targets = append(targets, orphaned...)
+	}
+
+	if cc.cache {
+		targets = append(targets, existingDirs(cc.CacheDir)...)
+	}
+
+	return targets, noUnlock, nil
+}
+
+// fileTargets returns the working directories of selected files. Files are locked, so
+// directories are not removed while another tau run is using them
+func (cc *cleanCmd) fileTargets() ([]string, func(), error) {
+	files, err := cc.load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock := func() {}
+	if !cc.dryRun {
+		if unlock, err = cc.lockFiles(files); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	targets := []string{}
+	for _, file := range files {
+		targets = append(targets, existingDirs(file.TempDir)...)
+	}
+
+	return targets, unlock, nil
+}
+
+// checkNoLocks returns an error if any tau run holds a lock on a file in tau directory dir
+func checkNoLocks(dir string) error {
+	locks := []string{}
+
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".lock") {
+			locks = append(locks, relativeToWorkingDir(path))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(locks) > 0 {
+		return errors.Errorf("files are locked by other tau runs (%s). Wait for them to finish, "+
+			"or use --force to remove files anyway", strings.Join(locks, ", "))
+	}
+
+	return nil
+}
+
+// orphanedTargets returns all directories in tau directory where the tau file no longer
+// exists. Files selected with -f are not used, so directories of other files that still
+// exist are never removed
+func (cc *cleanCmd) orphanedTargets() ([]string, error) {
+	return loader.OrphanedDirs(cc.TauDir)
+}
+
+// existingDirs returns the directories in dirs that exists
+func existingDirs(dirs ...string) []string {
+	existing := []string{}
+
+	for _, dir := range dirs {
+		if paths.IsDir(dir) {
+			existing = append(existing, dir)
+		}
+	}
+
+	return existing
+}
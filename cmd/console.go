@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+type consoleCmd struct {
+	meta
+
+	expressions []string
+	resolve     bool
+	output      string
+}
+
+var (
+	validConsoleFormats = []string{"hcl", "json"}
+
+	// consoleRequiresSingleFile is returned if console tries to load multiple files
+	consoleRequiresSingleFile = errors.Errorf("console can only evaluate expressions for a single file")
+
+	// invalidConsoleFormat is returned if output format is not one of validConsoleFormats
+	invalidConsoleFormat = errors.Errorf("invalid output format. Valid formats are %s", validConsoleFormats)
+
+	// valueNotKnown is returned if expression cannot be evaluated to a known value
+	valueNotKnown = errors.Errorf("value is not known, dependencies may not have been resolved")
+
+	// consoleLong is long description of console command
+	consoleLong = templates.LongDesc(`Evaluate expressions in the context of a file. Expressions
+		can use the same variables and functions as the file, like source.name, module.path
+		and env("NAME").
+
+		Use --resolve to resolve dependencies and data sources before evaluating, then
+		dependency.NAME.outputs and data sources referenced in inputs can be evaluated too.
+		Resolving dependencies requires terraform, same as plan.
+
+		Without --expression it starts an interactive console that reads one expression
+		per line. Type exit or press Ctrl-D to quit.
+		`)
+
+	// consoleExample is examples for console command
+	consoleExample = templates.Examples(`
+		# Start interactive console for module.hcl
+		tau console -f module.hcl
+
+		# Evaluate a single expression
+		tau console -f module.hcl -e 'source.name'
+
+		# Print dependency outputs as json
+		tau console -f module.hcl --resolve -e 'dependency.vnet.outputs' -o json
+	`)
+)
+
+// newConsoleCmd creates a new console command
+func newConsoleCmd() *cobra.Command {
+	cc := &consoleCmd{}
+
+	consoleCmd := &cobra.Command{
+		Use:                   "console -f SOURCE [-e EXPRESSION]",
+		Aliases:               []string{"eval"},
+		Short:                 "Evaluate expressions in the context of a file",
+		Long:                  consoleLong,
+		Example:               consoleExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cc.processArgs(args); err != nil {
+				return err
+			}
+
+			if err := cc.meta.init(args); err != nil {
+				return err
+			}
+
+			return cc.run(args)
+		},
+	}
+
+	f := consoleCmd.Flags()
+	f.StringArrayVarP(&cc.expressions, "expression", "e", []string{}, "expression to evaluate, can be repeated")
+	f.BoolVar(&cc.resolve, "resolve", false, "resolve dependencies and data sources before evaluating")
+	f.StringVarP(&cc.output, "output", "o", "hcl", "output format of values (hcl or json)")
+	f.DurationVar(&cc.lockTimeout, "lock-timeout", 0, "time to wait for other tau runs to release lock on a module")
+
+	cc.addMetaFlags(consoleCmd)
+
+	return consoleCmd
+}
+
+// processArgs process arguments and checks for invalid options or combination of arguments
+func (cc *consoleCmd) processArgs(args []string) error {
+	// terraform is only needed when resolving dependencies
+	cc.noTerraform = !cc.resolve
+
+	cc.output = strings.ToLower(cc.output)
+
+	for _, format := range validConsoleFormats {
+		if format == cc.output {
+			return nil
+		}
+	}
+
+	return invalidConsoleFormat
+}
+
+func (cc *consoleCmd) run(args []string) error {
+	// load all sources
+	files, err := cc.load()
+	if err != nil {
+		return err
+	}
+
+	if len(files) > 1 {
+		return consoleRequiresSingleFile
+	}
+
+	file := files[0]
+
+	if cc.resolve {
+		if err := cc.resolveFile(file); err != nil {
+			return err
+		}
+	}
+
+	ui.NewLine()
+
+	if len(cc.expressions) > 0 {
+		for _, expression := range cc.expressions {
+			if err := cc.evaluate(file, expression); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return cc.interactive(file)
+}
+
+// resolveFile runs prepare hooks and resolves the dependencies and data sources of file
+// so they are added to evaluation context. File is locked while resolving, as that writes
+// to its tau directory
+func (cc *consoleCmd) resolveFile(file *loader.ParsedFile) error {
+	unlock, err := cc.lockFiles(loader.ParsedFileCollection{file})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file.Log.Header("Executing prepare hooks...")
+
+	if err := cc.Runner.Run(file, "prepare", "console"); err != nil {
+		return err
+	}
+
+	file.Log.Header("Resolving dependencies...")
+
+	success, err := cc.Engine.ResolveDependencies(file)
+	if err != nil {
+		return err
+	}
+
+	if !success {
+		file.Log.Warn("Some of the dependencies failed to resolve, they cannot be evaluated")
+	}
+
+	return nil
+}
+
+// interactive reads expressions from input until end of input or exit, and evaluates
+// each of them. Errors in expressions are printed, but does not stop the console.
+func (cc *consoleCmd) interactive(file *loader.ParsedFile) error {
+	for {
+		line, err := ui.Ask(">")
+		if err == io.EOF {
+			ui.NewLine()
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+
+		if err := cc.evaluate(file, line); err != nil {
+			ui.Error("Error: %s", err)
+		}
+	}
+}
+
+// evaluate parses and evaluates expression in evaluation context of file, and prints
+// the value in output format
+func (cc *consoleCmd) evaluate(file *loader.ParsedFile, expression string) error {
+	expr, diags := hclsyntax.ParseExpression([]byte(expression), "<console>", hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	value, diags := expr.Value(file.EvalContext())
+	if diags.HasErrors() {
+		return diags
+	}
+
+	if !value.IsWhollyKnown() {
+		return valueNotKnown
+	}
+
+	formatted, err := formatValue(value, cc.output)
+	if err != nil {
+		return err
+	}
+
+	ui.Output("%s", formatted)
+
+	return nil
+}
+
+// formatValue returns value formatted as hcl or json
+func formatValue(value cty.Value, format string) (string, error) {
+	if format == "json" {
+		bytes, err := json.MarshalIndent(ctyjson.SimpleJSONValue{Value: value}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+
+		return string(bytes), nil
+	}
+
+	f := hclwrite.NewEmptyFile()
+	f.Body().AppendUnstructuredTokens(hclwrite.TokensForValue(value))
+
+	return string(hclwrite.Format(f.Bytes())), nil
+}
@@ -23,6 +23,9 @@ var (
 	destroyLong = templates.LongDesc(`Destroy resources managed by a module. It
 		can either destroy a single resource or all of them. Requires that the 
 		module have been initialized first.
+
+		Files are destroyed in reverse dependency order, so a deployment is always
+		destroyed before the deployments it depends on.
 		`)
 
 	// destroyExample is examples for destroy command
@@ -61,6 +64,8 @@ func newDestroyCmd() *cobra.Command {
 	f.BoolVar(&dc.autoApprove, "auto-approve", false, "auto approve destruction")
 
 	dc.addMetaFlags(destroyCmd)
+	dc.addSelectionFlags(destroyCmd)
+	dc.addWalkFlags(destroyCmd)
 
 	return destroyCmd
 }
@@ -72,11 +77,6 @@ func (dc *destroyCmd) run(args []string) error {
 		return err
 	}
 
-	// Want to destroy them in reverse order
-	for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
-		files[i], files[j] = files[j], files[i]
-	}
-
 	// Verify all modules have been initialized
 	if dc.meta.noAutoInit {
 		if err := files.IsAllInitialized(); err != nil {
@@ -84,10 +84,9 @@ func (dc *destroyCmd) run(args []string) error {
 		}
 	}
 
-	for _, file := range files {
-		if err := dc.runFile(file); err != nil {
-			return err
-		}
+	// Destroy files before the dependencies they use
+	if err := dc.walkReverse(files, dc.runFile); err != nil {
+		return err
 	}
 
 	ui.NewLine()
@@ -96,17 +95,19 @@ func (dc *destroyCmd) run(args []string) error {
 }
 
 func (dc *destroyCmd) runFile(file *loader.ParsedFile) error {
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
 
 	// Running prepare hook
 
-	ui.Header("Executing prepare hooks...")
+	file.Log.Header("Executing prepare hooks...")
 
-	if err := dc.Runner.Run(file, "prepare", "destroy"); err != nil {
+	if err := dc.runHooks(file, "prepare", "destroy"); err != nil {
 		return err
 	}
 
-	dc.autoInit(file)
+	if err := dc.autoInit(file); err != nil {
+		return err
+	}
 
 	// Resolving dependencies
 
@@ -123,19 +124,19 @@ func (dc *destroyCmd) runFile(file *loader.ParsedFile) error {
 
 	// Executing terraform command
 
-	ui.NewLine()
-	ui.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
-	ui.NewLine()
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
 
 	if !paths.IsFile(file.VariableFile()) {
-		ui.Warn("No values file exists")
+		file.Log.Warn("No values file exists")
 		return nil
 	}
 
 	options := &shell.Options{
 		WorkingDirectory: file.ModuleDir(),
-		Stdout:           shell.Processors(processors.NewUI(ui.Info)),
-		Stderr:           shell.Processors(processors.NewUI(ui.Error)),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
 		Env:              file.Env,
 	}
 
@@ -145,7 +146,7 @@ func (dc *destroyCmd) runFile(file *loader.ParsedFile) error {
 		extraArgs = append(extraArgs, "-auto-approve")
 	}
 
-	if err := dc.Engine.Executor.Execute(options, "destroy", extraArgs...); err != nil {
+	if err := dc.execute(file, options, "destroy", extraArgs...); err != nil {
 		return err
 	}
 
@@ -153,9 +154,9 @@ func (dc *destroyCmd) runFile(file *loader.ParsedFile) error {
 
 	// Executing finish hook
 
-	ui.Header("Executing finish hooks...")
+	file.Log.Header("Executing finish hooks...")
 
-	if err := dc.Runner.Run(file, "finish", "destroy"); err != nil {
+	if err := dc.runHooks(file, "finish", "destroy"); err != nil {
 		return err
 	}
 
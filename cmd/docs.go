@@ -0,0 +1,522 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/paths"
+	"github.com/avinor/tau/pkg/helpers/ui"
+	"github.com/avinor/tau/pkg/scaffold"
+)
+
+type docsCmd struct {
+	meta
+
+	outputDir string
+	filename  string
+}
+
+var (
+	// maskedValue replaces values of sensitive inputs
+	maskedValue = `"(sensitive)"`
+
+	// maskedCtyValue replaces sensitive values nested in objects and maps of inputs
+	maskedCtyValue = cty.StringVal("(sensitive)")
+
+	// sensitiveNameRegexp matches input names that are treated as sensitive, even if module
+	// does not mark them as sensitive
+	sensitiveNameRegexp = regexp.MustCompile("(?i)(password|passwd|secret|token|private_key|access_key|api_key|credential|connection_string|(^|_)sas(_|$))")
+
+	// anchorRegexp matches characters github removes when creating anchors for headings
+	anchorRegexp = regexp.MustCompile("[^a-z0-9_ -]")
+
+	// backendKeyAttributes are the attributes used as state key by different backends
+	backendKeyAttributes = []string{"key", "prefix", "path", "workspace_key_prefix"}
+
+	// docsLong is long description of docs command
+	docsLong = templates.LongDesc(`Generate documentation of deployments as markdown. It writes
+		one page for each folder, with a section for each deployment in folder. A section
+		lists module source and version, backend type and state key, dependencies, data
+		sources, hooks with the events that trigger them and the inputs that are set.
+
+		Inputs are shown as evaluated values where they can be evaluated without resolving
+		dependencies, otherwise as the expression in source. Inputs the module declares as
+		sensitive are masked. Modules are only read if they have been initialized, so run
+		tau init first to detect them, the page has a warning if they are not. Inputs, and
+		keys of objects and maps in inputs, with names like password, secret or token are
+		always masked.
+
+		Pages are printed to stdout, or written to --output-dir using same folder structure
+		as the tau files. Use --output-dir . to write pages next to the tau files.
+		`)
+
+	// docsExample is examples for docs command
+	docsExample = templates.Examples(`
+		# Print documentation of deployments in current folder
+		tau docs
+
+		# Write a DEPLOYMENTS.md file next to the tau files in current folder
+		tau docs --output-dir .
+
+		# Write documentation of prod folder to docs/prod/README.md
+		tau docs -f prod --output-dir docs --filename README.md
+	`)
+)
+
+// newDocsCmd creates a new docs command
+func newDocsCmd() *cobra.Command {
+	dc := &docsCmd{
+		meta: meta{
+			noTerraform: true,
+		},
+	}
+
+	docsCmd := &cobra.Command{
+		Use:                   "docs [-f SOURCE]",
+		Short:                 "Generate markdown documentation of deployments",
+		Long:                  docsLong,
+		Example:               docsExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dc.meta.init(args); err != nil {
+				return err
+			}
+
+			return dc.run(args)
+		},
+	}
+
+	f := docsCmd.Flags()
+	f.StringVar(&dc.outputDir, "output-dir", "", "write pages to directory instead of stdout")
+	f.StringVar(&dc.filename, "filename", "DEPLOYMENTS.md", "name of page written to each folder")
+
+	dc.addMetaFlags(docsCmd)
+	dc.addSelectionFlags(docsCmd)
+
+	return docsCmd
+}
+
+func (dc *docsCmd) run(args []string) error {
+	// load all sources
+	files, err := dc.load()
+	if err != nil {
+		return err
+	}
+
+	folders := map[string][]*loader.ParsedFile{}
+	for _, file := range files {
+		dir := filepath.Dir(file.FullPath)
+		folders[dir] = append(folders[dir], file)
+	}
+
+	dirs := []string{}
+	for dir := range folders {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		content, err := generateDocsPage(dir, folders[dir])
+		if err != nil {
+			return err
+		}
+
+		if err := dc.writePage(dir, content); err != nil {
+			return err
+		}
+	}
+
+	ui.NewLine()
+
+	return nil
+}
+
+// writePage prints the page for folder dir, or writes it to output directory
+func (dc *docsCmd) writePage(dir string, content []byte) error {
+	if dc.outputDir == "" {
+		ui.Output("%s", strings.TrimRight(string(content), "\n"))
+		ui.Output("")
+		return nil
+	}
+
+	dest := filepath.Join(paths.Abs(workingDir, dc.outputDir), relativeToWorkingDir(dir), dc.filename)
+	paths.EnsureDirectoryExists(filepath.Dir(dest))
+
+	ui.Info("- Writing %s", relativeToWorkingDir(dest))
+
+	return ioutil.WriteFile(dest, content, os.ModePerm)
+}
+
+// generateDocsPage returns the markdown page for all files in folder dir. Files are
+// documented in same order as they were loaded
+func generateDocsPage(dir string, files []*loader.ParsedFile) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	title := filepath.ToSlash(relativeToWorkingDir(dir))
+	if title == "." {
+		title = filepath.Base(dir)
+	}
+
+	fmt.Fprintf(&buffer, "# %s\n\n", title)
+
+	fmt.Fprintln(&buffer, "| Deployment | Module | Version | Backend | Key |")
+	fmt.Fprintln(&buffer, "|---|---|---|---|---|")
+
+	for _, file := range files {
+		module := file.Config.Module
+		backendType, key := docsBackend(file)
+
+		fmt.Fprintf(&buffer, "| [%s](#%s) | `%s` | %s | %s | %s |\n", file.Name, markdownAnchor(file.Name),
+			module.Source, markdownOrDash(module.Version), markdownOrDash(backendType), markdownOrDash(key))
+	}
+
+	for _, file := range files {
+		section, err := generateDocsSection(file)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.WriteString("\n")
+		buffer.Write(section)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// generateDocsSection returns the markdown section documenting a single file
+func generateDocsSection(file *loader.ParsedFile) ([]byte, error) {
+	var buffer bytes.Buffer
+	cfg := file.Config
+
+	fmt.Fprintf(&buffer, "## %s\n\n", file.Name)
+
+	if file.ShouldDelete {
+		fmt.Fprintf(&buffer, "> Marked for deletion\n\n")
+	}
+
+	fmt.Fprintf(&buffer, "- **Module:** `%s`\n", cfg.Module.Source)
+	if cfg.Module.Version != "" {
+		fmt.Fprintf(&buffer, "- **Version:** %s\n", cfg.Module.Version)
+	}
+
+	if backendType, key := docsBackend(file); backendType != "" {
+		fmt.Fprintf(&buffer, "- **Backend:** %s\n", backendType)
+
+		if key != "" {
+			fmt.Fprintf(&buffer, "- **State key:** `%s`\n", key)
+		}
+	}
+
+	if cfg.Workspace != "" {
+		fmt.Fprintf(&buffer, "- **Workspace:** %s\n", cfg.Workspace)
+	}
+
+	if len(cfg.Dependencies) > 0 {
+		fmt.Fprintf(&buffer, "\n### Dependencies\n\n")
+		fmt.Fprintln(&buffer, "| Name | Source |")
+		fmt.Fprintln(&buffer, "|---|---|")
+
+		for _, dep := range cfg.Dependencies {
+			fmt.Fprintf(&buffer, "| %s | `%s` |\n", dep.Name, dep.Source)
+		}
+	}
+
+	if len(cfg.Datas) > 0 {
+		fmt.Fprintf(&buffer, "\n### Data sources\n\n")
+
+		for _, data := range cfg.Datas {
+			fmt.Fprintf(&buffer, "- `%s.%s`\n", data.Type, data.Name)
+		}
+	}
+
+	if len(cfg.Hooks) > 0 {
+		fmt.Fprintf(&buffer, "\n### Hooks\n\n")
+		fmt.Fprintln(&buffer, "| Name | Trigger on | Runs |")
+		fmt.Fprintln(&buffer, "|---|---|---|")
+
+		for _, hook := range cfg.Hooks {
+			fmt.Fprintf(&buffer, "| %s | %s | %s |\n", hook.Type,
+				markdownOrDash(stringValue(hook.TriggerOn)), markdownOrDash(hookCommand(hook)))
+		}
+	}
+
+	inputs, err := docsInputs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inputs) > 0 {
+		fmt.Fprintf(&buffer, "\n### Inputs\n\n")
+
+		if !file.IsInitialized() {
+			fmt.Fprintf(&buffer, "> Module has not been initialized, inputs it declares as sensitive are not masked. Run tau init first.\n\n")
+		}
+		fmt.Fprintf(&buffer, "```hcl\n%s```\n", inputs)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// docsBackend returns the backend type and state key of file. Key is empty if it cannot
+// be evaluated, or backend does not use any of the known key attributes
+func docsBackend(file *loader.ParsedFile) (string, string) {
+	backend := file.Config.Backend
+	if backend == nil {
+		return "", ""
+	}
+
+	attrs, diags := backend.Config.JustAttributes()
+	if diags.HasErrors() {
+		return backend.Type, ""
+	}
+
+	for _, name := range backendKeyAttributes {
+		attr, ok := attrs[name]
+		if !ok {
+			continue
+		}
+
+		value, diags := attr.Expr.Value(file.EvalContext())
+		if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+			return backend.Type, expressionSource(attr.Expr)
+		}
+
+		if formatted, err := formatValue(value, "hcl"); err == nil {
+			return backend.Type, strings.Trim(strings.TrimSpace(formatted), `"`)
+		}
+	}
+
+	return backend.Type, ""
+}
+
+// docsInputs returns the inputs of file as hcl attributes, sorted by name. Sensitive
+// inputs are masked
+func docsInputs(file *loader.ParsedFile) ([]byte, error) {
+	if file.Config.Inputs == nil {
+		return nil, nil
+	}
+
+	attrs, diags := file.Config.Inputs.Config.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	sensitive := sensitiveVariables(file)
+
+	names := []string{}
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+
+	for _, name := range names {
+		fmt.Fprintf(&buffer, "%s = %s\n", name, inputValue(file, attrs[name], sensitive[name]))
+	}
+
+	return hclwrite.Format(buffer.Bytes()), nil
+}
+
+// inputValue returns the value of input attr as it should be documented. Values that can
+// be evaluated without resolving dependencies are evaluated, others are returned as written
+// in source
+func inputValue(file *loader.ParsedFile, attr *hcl.Attribute, sensitive bool) string {
+	if sensitive || sensitiveNameRegexp.MatchString(attr.Name) {
+		return maskedValue
+	}
+
+	return expressionValue(file, attr.Expr)
+}
+
+// expressionValue returns the value of expr as it should be documented, with values of
+// sensitive keys in objects and maps masked. Object and list expressions that cannot be
+// evaluated are documented item by item, so sensitive items are masked also then
+func expressionValue(file *loader.ParsedFile, expr hcl.Expression) string {
+	value, diags := expr.Value(file.EvalContext())
+	if !diags.HasErrors() && value.IsWhollyKnown() {
+		if formatted, err := formatValue(maskSensitiveValue(value), "hcl"); err == nil {
+			return strings.TrimSpace(formatted)
+		}
+	}
+
+	if tuple, ok := expr.(*hclsyntax.TupleConsExpr); ok {
+		items := []string{}
+		for _, item := range tuple.Exprs {
+			items = append(items, expressionValue(file, item))
+		}
+
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	}
+
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return expressionSource(expr)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("{\n")
+
+	for _, item := range object.Items {
+		key := hcl.ExprAsKeyword(item.KeyExpr)
+		if key == "" {
+			if keyValue, diags := item.KeyExpr.Value(file.EvalContext()); !diags.HasErrors() &&
+				keyValue.IsKnown() && !keyValue.IsNull() && keyValue.Type() == cty.String {
+				key = keyValue.AsString()
+			}
+		}
+
+		value := maskedValue
+		if key == "" || !sensitiveNameRegexp.MatchString(key) {
+			value = expressionValue(file, item.ValueExpr)
+		}
+
+		fmt.Fprintf(&buffer, "%s = %s\n", expressionSource(item.KeyExpr), value)
+	}
+
+	buffer.WriteString("}")
+
+	return buffer.String()
+}
+
+// maskSensitiveValue returns value with values of all keys in objects and maps that match
+// sensitiveNameRegexp masked. Maps and lists are returned as objects and tuples, as masked
+// values may not have same type as other elements
+func maskSensitiveValue(value cty.Value) cty.Value {
+	if value.IsNull() || !value.IsKnown() {
+		return value
+	}
+
+	ty := value.Type()
+
+	switch {
+	case ty.IsObjectType() || ty.IsMapType():
+		if value.LengthInt() == 0 {
+			return value
+		}
+
+		values := map[string]cty.Value{}
+		for it := value.ElementIterator(); it.Next(); {
+			key, val := it.Element()
+
+			if sensitiveNameRegexp.MatchString(key.AsString()) {
+				values[key.AsString()] = maskedCtyValue
+			} else {
+				values[key.AsString()] = maskSensitiveValue(val)
+			}
+		}
+
+		return cty.ObjectVal(values)
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		if value.LengthInt() == 0 {
+			return value
+		}
+
+		values := []cty.Value{}
+		for it := value.ElementIterator(); it.Next(); {
+			_, val := it.Element()
+			values = append(values, maskSensitiveValue(val))
+		}
+
+		return cty.TupleVal(values)
+	}
+
+	return value
+}
+
+// sensitiveVariables returns the variables module of file declares as sensitive. Module is
+// only read if it has been initialized
+func sensitiveVariables(file *loader.ParsedFile) map[string]bool {
+	sensitive := map[string]bool{}
+
+	if !file.IsInitialized() {
+		return sensitive
+	}
+
+	variables, err := scaffold.ParseVariables(file.ModuleDir())
+	if err != nil {
+		ui.Warn("Could not read variables of module for %s: %s", file.Name, err)
+		return sensitive
+	}
+
+	for _, variable := range variables {
+		if variable.Sensitive {
+			sensitive[variable.Name] = true
+		}
+	}
+
+	return sensitive
+}
+
+// expressionSource returns the expression as written in source file
+func expressionSource(expr hcl.Expression) string {
+	rng := expr.Range()
+
+	src, ok := config.Sources()[rng.Filename]
+	if !ok {
+		return "(unknown)"
+	}
+
+	return strings.TrimSpace(string(rng.SliceBytes(src.Bytes)))
+}
+
+// hookCommand returns the command, or script, a hook runs with its arguments
+func hookCommand(hook *config.Hook) string {
+	cmd := stringValue(hook.Command)
+	if cmd == "" {
+		cmd = stringValue(hook.Script)
+	}
+
+	if cmd == "" {
+		return ""
+	}
+
+	if hook.Arguments != nil {
+		cmd = strings.Join(append([]string{cmd}, *hook.Arguments...), " ")
+	}
+
+	return fmt.Sprintf("`%s`", cmd)
+}
+
+// stringValue returns value of str, or empty string if it is nil
+func stringValue(str *string) string {
+	if str == nil {
+		return ""
+	}
+
+	return *str
+}
+
+// markdownOrDash returns str, or a dash if it is empty so table cells are never empty
+func markdownOrDash(str string) string {
+	if str == "" {
+		return "-"
+	}
+
+	return strings.Replace(str, "|", "\\|", -1)
+}
+
+// markdownAnchor returns the anchor github generates for a heading with text
+func markdownAnchor(text string) string {
+	anchor := anchorRegexp.ReplaceAllString(strings.ToLower(text), "")
+
+	return strings.Replace(anchor, " ", "-", -1)
+}
@@ -0,0 +1,382 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/paths"
+	"github.com/avinor/tau/pkg/helpers/ui"
+	"github.com/avinor/tau/pkg/shell"
+	"github.com/avinor/tau/pkg/shell/processors"
+	"github.com/avinor/tau/pkg/terraform/def"
+)
+
+const (
+	// driftExitCode is returned when any of the deployments have drifted
+	driftExitCode = 2
+)
+
+type driftCmd struct {
+	meta
+
+	output string
+
+	// results of each checked file, guarded by lock
+	results map[*loader.ParsedFile]*def.PlanSummary
+	lock    sync.Mutex
+}
+
+// driftReportFile is the content of drift report written after checking for drift
+type driftReportFile struct {
+	Checked     time.Time          `json:"checked"`
+	Drifted     bool               `json:"drifted"`
+	Deployments []*driftDeployment `json:"deployments"`
+}
+
+// driftDeployment is the drift of a single deployment. Resources is empty if the
+// deployment has not drifted, or it could not be checked. Error is set if checking the
+// deployment failed
+type driftDeployment struct {
+	Name      string                `json:"name"`
+	Path      string                `json:"path"`
+	Checked   bool                  `json:"checked"`
+	Drifted   bool                  `json:"drifted"`
+	Error     string                `json:"error,omitempty"`
+	Resources []*def.ResourceChange `json:"resources"`
+}
+
+var (
+	// driftLong is long description of drift command
+	driftLong = templates.LongDesc(`Check deployments for drift between configuration and the
+		real infrastructure. Command will run prepare hooks for plan, resolve dependencies and
+		create input variables, same as plan, and run a plan that refreshes state. It does not
+		change any resources and does not replace the plan created by tau plan. Finish hooks
+		are run for drift.
+
+		When all files have been checked it prints the deployments that have drifted, and
+		which resources would be changed to bring them back in line with configuration. The
+		report is written to .tau/drift-report.json, or file set with --output. Report is
+		also written when checking some of the deployments failed, with the error of each.
+
+		Tau exits with 0 if no deployment has drifted, 1 on errors and 2 if any deployment
+		has drifted, so it can be scheduled to run regularly.
+
+		Files marked for deletion are not checked.
+		`)
+
+	// driftExample is examples for drift command
+	driftExample = templates.Examples(`
+		# Check all deployments in current folder for drift
+		tau drift
+
+		# Check a single module and write report to drift.json
+		tau drift -f module.hcl --output drift.json
+	`)
+)
+
+// newDriftCmd creates a new drift command
+func newDriftCmd() *cobra.Command {
+	dc := &driftCmd{
+		results: map[*loader.ParsedFile]*def.PlanSummary{},
+	}
+
+	driftCmd := &cobra.Command{
+		Use:                   "drift [-f SOURCE]",
+		Short:                 "Check deployments for drift",
+		Long:                  driftLong,
+		Example:               driftExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dc.meta.init(args); err != nil {
+				return err
+			}
+
+			return dc.run(args)
+		},
+	}
+
+	f := driftCmd.Flags()
+	f.StringVarP(&dc.output, "output", "o", "", "file to write drift report to (default .tau/drift-report.json)")
+
+	dc.addMetaFlags(driftCmd)
+	dc.addSelectionFlags(driftCmd)
+	dc.addWalkFlags(driftCmd)
+
+	return driftCmd
+}
+
+func (dc *driftCmd) run(args []string) error {
+	// load all sources
+	files, err := dc.load()
+	if err != nil {
+		return err
+	}
+
+	// Verify all modules have been initialized
+	if dc.meta.noAutoInit {
+		if err := files.IsAllInitialized(); err != nil {
+			return err
+		}
+	}
+
+	// report is written also when some files failed, so failed deployments are reported
+	walkErr := dc.walk(files, dc.runFile)
+
+	report := dc.newReportFile(files)
+
+	dc.printReport(report)
+
+	if err := dc.writeReport(report); err != nil {
+		return err
+	}
+
+	ui.NewLine()
+
+	if walkErr != nil {
+		return walkErr
+	}
+
+	if report.Drifted {
+		return &ExitCodeError{Code: driftExitCode}
+	}
+
+	return nil
+}
+
+func (dc *driftCmd) runFile(file *loader.ParsedFile) error {
+	file.Log.Separator(file.Name)
+
+	if file.ShouldDelete {
+		file.Log.Warn("%s is marked for deletion, not checking for drift", file.Name)
+		return nil
+	}
+
+	// Running prepare hook
+
+	file.Log.Header("Executing prepare hooks...")
+
+	// Drift runs a plan, so it needs same prepare hooks as plan, for instance to set credentials
+	if err := dc.runHooks(file, "prepare", "plan"); err != nil {
+		return err
+	}
+
+	if err := dc.autoInit(file); err != nil {
+		return err
+	}
+
+	// Resolving dependencies
+
+	success, err := dc.resolveDependencies(file)
+	if err != nil {
+		return err
+	}
+
+	if !success {
+		return nil
+	}
+
+	// Executing terraform command
+
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
+
+	if !paths.IsFile(file.VariableFile()) {
+		file.Log.Warn("Cannot check %s for drift", file.Name)
+		return nil
+	}
+
+	options := &shell.Options{
+		WorkingDirectory: file.ModuleDir(),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
+		Env:              file.Env,
+	}
+
+	extraArgs := getExtraArgs(dc.Engine.Compatibility.GetInvalidArgs("plan")...)
+	extraArgs = append(extraArgs, "-input=false", "-detailed-exitcode", fmt.Sprintf("-out=%s", file.DriftPlanFile()))
+
+	done := dc.report.startPhase(file, phaseCommand)
+
+	err = dc.Engine.Executor.Execute(options, "plan", extraArgs...)
+
+	// exit code 2 means that plan succeeded and there are changes
+	drifted := false
+	if exitErr, ok := err.(*shell.ExitError); ok && exitErr.ExitCode == 2 {
+		drifted = true
+		err = nil
+	}
+
+	done(err)
+
+	if err != nil {
+		return err
+	}
+
+	if err := dc.summarizeDrift(file, drifted); err != nil {
+		return err
+	}
+
+	// Executing finish hook
+
+	file.Log.Header("Executing finish hooks...")
+
+	if err := dc.runHooks(file, "finish", "drift"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// summarizeDrift saves the resources changed in drift plan for file, and removes the
+// plan file afterwards as it should never be applied
+func (dc *driftCmd) summarizeDrift(file *loader.ParsedFile, drifted bool) error {
+	defer os.Remove(file.DriftPlanFile())
+
+	summary := &def.PlanSummary{}
+
+	if drifted {
+		options := &shell.Options{
+			WorkingDirectory: file.ModuleDir(),
+			Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
+			Env:              file.Env,
+		}
+
+		s, err := dc.Engine.Executor.SummarizePlan(options, file.DriftPlanFile())
+		if err != nil {
+			return err
+		}
+
+		summary = s
+	}
+
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	dc.results[file] = summary
+
+	return nil
+}
+
+// newReportFile creates the drift report for all files, in same order as they were loaded
+func (dc *driftCmd) newReportFile(files loader.ParsedFileCollection) *driftReportFile {
+	report := &driftReportFile{
+		Checked:     time.Now().UTC(),
+		Deployments: []*driftDeployment{},
+	}
+
+	for _, file := range files {
+		deployment := &driftDeployment{
+			Name:      file.Name,
+			Path:      relativeToWorkingDir(file.FullPath),
+			Resources: []*def.ResourceChange{},
+		}
+
+		if summary, ok := dc.results[file]; ok {
+			deployment.Checked = true
+			deployment.Drifted = summary.HasChanges()
+
+			if summary.Resources != nil {
+				deployment.Resources = summary.Resources
+			}
+		}
+
+		if result := dc.report.result(file); result.Status == walkFailed {
+			deployment.Error = "failed"
+			if result.Err != nil {
+				deployment.Error = result.Err.Error()
+			}
+		}
+
+		if deployment.Drifted {
+			report.Drifted = true
+		}
+
+		report.Deployments = append(report.Deployments, deployment)
+	}
+
+	return report
+}
+
+// printReport prints a table with status of each deployment, and the resources changed
+// in each deployment that has drifted
+func (dc *driftCmd) printReport(report *driftReportFile) {
+	var buffer bytes.Buffer
+
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, "DEPLOYMENT\tSTATUS\tRESOURCES\t")
+
+	for _, deployment := range report.Deployments {
+		switch {
+		case deployment.Error != "":
+			fmt.Fprintf(writer, "%s\terror\t-\t\n", deployment.Name)
+		case !deployment.Checked:
+			fmt.Fprintf(writer, "%s\tnot checked\t-\t\n", deployment.Name)
+		case deployment.Drifted:
+			fmt.Fprintf(writer, "%s\tdrifted\t%v\t\n", deployment.Name, len(deployment.Resources))
+		default:
+			fmt.Fprintf(writer, "%s\tin sync\t0\t\n", deployment.Name)
+		}
+	}
+
+	writer.Flush()
+
+	ui.Separator("Drift report")
+
+	lines := strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n")
+	for idx, line := range lines {
+		line = strings.TrimRight(line, " ")
+
+		switch {
+		case idx == 0:
+			ui.Info("%s", color.New(color.Bold).Sprint(line))
+		case report.Deployments[idx-1].Drifted, report.Deployments[idx-1].Error != "":
+			ui.Info("%s", color.RedString(line))
+		default:
+			ui.Info("%s", line)
+		}
+	}
+
+	for _, deployment := range report.Deployments {
+		if !deployment.Drifted {
+			continue
+		}
+
+		ui.NewLine()
+		ui.Info("%s", color.New(color.Bold).Sprint(deployment.Name))
+
+		for _, resource := range deployment.Resources {
+			ui.Info("  %-8s %s", resource.Action, resource.Address)
+		}
+	}
+}
+
+// writeReport writes the report as json to output file, or drift report file in tau directory
+func (dc *driftCmd) writeReport(report *driftReportFile) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	output := paths.Join(dc.TauDir, "drift-report.json")
+	if dc.output != "" {
+		output = paths.Abs(workingDir, dc.output)
+	}
+
+	return ioutil.WriteFile(output, content, os.ModePerm)
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+type graphCmd struct {
+	meta
+
+	output string
+}
+
+// graphNode is a single deployment in the dependency graph. External nodes are dependencies
+// that are not part of the loaded files, they will not be processed when walking files.
+type graphNode struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Path         string            `json:"path"`
+	Includes     []string          `json:"includes"`
+	Destroy      bool              `json:"destroy"`
+	External     bool              `json:"external"`
+	Dependencies []graphDependency `json:"dependencies"`
+}
+
+// graphDependency is an edge from a deployment to one of its dependencies
+type graphDependency struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+}
+
+var (
+	validGraphFormats = []string{"dot", "json", "mermaid"}
+
+	// invalidGraphFormat is returned if output format is not one of validGraphFormats
+	invalidGraphFormat = errors.Errorf("invalid graph format. Valid formats are %s", validGraphFormats)
+
+	// graphLong is long description of graph command
+	graphLong = templates.LongDesc(`Print the dependency graph of deployments without running
+		any terraform commands. It uses the same graph as other commands when deciding in
+		which order to process files. Graph can be printed as Graphviz DOT, JSON or as a
+		Mermaid diagram.
+
+		Files marked for destruction (DELETE_ or DESTROY_ prefix) are highlighted, and
+		dependencies outside of the loaded files are marked as external.
+		`)
+
+	// graphExample is examples for graph command
+	graphExample = templates.Examples(`
+		# Print graph of current folder in DOT format
+		tau graph
+
+		# Render graph as an image with Graphviz
+		tau graph | dot -Tsvg > graph.svg
+
+		# Print graph as a Mermaid diagram
+		tau graph -f environments/prod --output mermaid
+	`)
+)
+
+// newGraphCmd creates a new graph command
+func newGraphCmd() *cobra.Command {
+	gc := &graphCmd{
+		meta: meta{
+			noTerraform: true,
+		},
+	}
+
+	graphCmd := &cobra.Command{
+		Use:                   "graph [-f SOURCE]",
+		Short:                 "Print the dependency graph of deployments",
+		Long:                  graphLong,
+		Example:               graphExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := gc.meta.init(args); err != nil {
+				return err
+			}
+
+			if err := gc.processArgs(args); err != nil {
+				return err
+			}
+
+			return gc.run(args)
+		},
+	}
+
+	f := graphCmd.Flags()
+	f.StringVarP(&gc.output, "output", "o", "dot", "output format of graph (dot, json or mermaid)")
+
+	gc.addMetaFlags(graphCmd)
+	gc.addSelectionFlags(graphCmd)
+
+	return graphCmd
+}
+
+// processArgs process arguments and checks for invalid options or combination of arguments
+func (gc *graphCmd) processArgs(args []string) error {
+	gc.output = strings.ToLower(gc.output)
+
+	for _, format := range validGraphFormats {
+		if format == gc.output {
+			return nil
+		}
+	}
+
+	return invalidGraphFormat
+}
+
+func (gc *graphCmd) run(args []string) error {
+	// load all sources
+	files, err := gc.load()
+	if err != nil {
+		return err
+	}
+
+	nodes := newGraphNodes(files)
+
+	ui.NewLine()
+
+	switch gc.output {
+	case "json":
+		bytes, err := json.MarshalIndent(nodes, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		ui.Output("%s", string(bytes))
+	case "mermaid":
+		ui.Output("%s", graphToMermaid(nodes))
+	default:
+		ui.Output("%s", graphToDot(nodes))
+	}
+
+	return nil
+}
+
+// newGraphNodes creates a node for each file in collection, and all their dependencies
+// that are outside of collection. Nodes are returned in load order, followed by external
+// dependencies. Edges are sorted by dependency name so output is stable.
+func newGraphNodes(files loader.ParsedFileCollection) []*graphNode {
+	graph := files.Graph()
+
+	nodes := []*graphNode{}
+	visited := map[*loader.ParsedFile]bool{}
+	queue := append([]*loader.ParsedFile{}, files...)
+
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+
+		if visited[file] {
+			continue
+		}
+		visited[file] = true
+
+		node := &graphNode{
+			ID:           graphNodeID(file),
+			Name:         file.Name,
+			Path:         file.FullPath,
+			Includes:     []string{},
+			Destroy:      file.ShouldDelete,
+			External:     !graph.HasVertex(file),
+			Dependencies: []graphDependency{},
+		}
+
+		for _, child := range file.Children() {
+			node.Includes = append(node.Includes, relativeToWorkingDir(child.FullPath))
+		}
+
+		names := []string{}
+		for name := range file.Dependencies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			dep := file.Dependencies[name]
+
+			node.Dependencies = append(node.Dependencies, graphDependency{
+				Name:   name,
+				Target: graphNodeID(dep),
+			})
+
+			queue = append(queue, dep)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// graphToDot returns the graph in Graphviz DOT format
+func graphToDot(nodes []*graphNode) string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph tau {\n")
+	sb.WriteString("\tnode [shape = \"box\"];\n\n")
+
+	for _, node := range nodes {
+		attrs := []string{
+			fmt.Sprintf("label = %q", strings.Join(graphNodeLabel(node), "\n")),
+		}
+
+		if node.Destroy {
+			attrs = append(attrs, "color = \"red\"", "fontcolor = \"red\"")
+		}
+
+		if node.External {
+			attrs = append(attrs, "style = \"dashed\"")
+		}
+
+		fmt.Fprintf(&sb, "\t%q [%s];\n", node.ID, strings.Join(attrs, ", "))
+	}
+
+	sb.WriteString("\n")
+
+	for _, node := range nodes {
+		for _, dep := range node.Dependencies {
+			fmt.Fprintf(&sb, "\t%q -> %q [label = %q];\n", node.ID, dep.Target, dep.Name)
+		}
+	}
+
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
+// graphToMermaid returns the graph as a Mermaid flowchart
+func graphToMermaid(nodes []*graphNode) string {
+	var sb strings.Builder
+
+	ids := map[string]string{}
+	external := map[string]bool{}
+	for idx, node := range nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", idx)
+		external[node.ID] = node.External
+	}
+
+	sb.WriteString("graph TD\n")
+
+	for _, node := range nodes {
+		label := strings.ReplaceAll(strings.Join(graphNodeLabel(node), "<br/>"), "\"", "#quot;")
+		fmt.Fprintf(&sb, "    %s[\"%s\"]\n", ids[node.ID], label)
+	}
+
+	for _, node := range nodes {
+		for _, dep := range node.Dependencies {
+			arrow := "-->"
+			if external[dep.Target] {
+				arrow = "-.->"
+			}
+
+			fmt.Fprintf(&sb, "    %s %s|%s| %s\n", ids[node.ID], arrow, dep.Name, ids[dep.Target])
+		}
+	}
+
+	sb.WriteString("    classDef destroy stroke:#f00,color:#f00\n")
+	sb.WriteString("    classDef external stroke-dasharray:5 5\n")
+
+	for _, node := range nodes {
+		if node.Destroy {
+			fmt.Fprintf(&sb, "    class %s destroy\n", ids[node.ID])
+		}
+
+		if node.External {
+			fmt.Fprintf(&sb, "    class %s external\n", ids[node.ID])
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// graphNodeLabel returns the lines to show in label for node
+func graphNodeLabel(node *graphNode) []string {
+	lines := []string{node.ID}
+
+	if len(node.Includes) > 0 {
+		lines = append(lines, fmt.Sprintf("includes %s", strings.Join(node.Includes, ", ")))
+	}
+
+	if node.Destroy {
+		lines = append(lines, "(destroy)")
+	}
+
+	if node.External {
+		lines = append(lines, "(external)")
+	}
+
+	return lines
+}
+
+// graphNodeID returns a unique id for file, the path relative to working directory
+func graphNodeID(file *loader.ParsedFile) string {
+	return relativeToWorkingDir(file.FullPath)
+}
+
+// relativeToWorkingDir returns path relative to working directory if possible,
+// otherwise it returns path unchanged
+func relativeToWorkingDir(path string) string {
+	rel, err := filepath.Rel(workingDir, path)
+	if err != nil {
+		return path
+	}
+
+	return rel
+}
@@ -86,6 +86,8 @@ func newInitCmd() *cobra.Command {
 	f.StringVar(&ic.options.source.Version, "source-version", "", "override module source version, only valid together with source override")
 
 	ic.addMetaFlags(initCmd)
+	ic.addSelectionFlags(initCmd)
+	ic.addWalkFlags(initCmd)
 
 	return initCmd
 }
@@ -128,7 +130,7 @@ func (ic *initCmd) run(args []string) error {
 		return sourceMustBeAFile
 	}
 
-	if err := files.Walk(ic.runFile); err != nil {
+	if err := ic.walk(files, ic.runFile); err != nil {
 		return err
 	}
 
@@ -138,13 +140,13 @@ func (ic *initCmd) run(args []string) error {
 }
 
 func (ic *initCmd) runFile(file *loader.ParsedFile) error {
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
 
 	// Running prepare hook
 
-	ui.Header("Executing prepare hooks...")
+	file.Log.Header("Executing prepare hooks...")
 
-	if err := ic.Runner.Run(file, "prepare", "init"); err != nil {
+	if err := ic.runHooks(file, "prepare", "init"); err != nil {
 		return err
 	}
 
@@ -156,9 +158,9 @@ func (ic *initCmd) runFile(file *loader.ParsedFile) error {
 
 	// Executing finish hook
 
-	ui.Header("Executing finish hooks...")
+	file.Log.Header("Executing finish hooks...")
 
-	if err := ic.Runner.Run(file, "finish", "init"); err != nil {
+	if err := ic.runHooks(file, "finish", "init"); err != nil {
 		return err
 	}
 
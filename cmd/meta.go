@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/avinor/tau/pkg/config"
 	"github.com/avinor/tau/pkg/config/loader"
@@ -14,6 +18,7 @@ import (
 	"github.com/avinor/tau/pkg/helpers/ui"
 	"github.com/avinor/tau/pkg/hooks"
 	hooksdef "github.com/avinor/tau/pkg/hooks/def"
+	"github.com/avinor/tau/pkg/lock"
 	"github.com/avinor/tau/pkg/shell"
 	"github.com/avinor/tau/pkg/shell/processors"
 	"github.com/avinor/tau/pkg/terraform"
@@ -23,13 +28,31 @@ import (
 var (
 	// noSourceInPath is returned when there are no source files in path
 	noSourceInPath = errors.Errorf("no source files found in path")
+
+	// fileSkipped is returned by walker functions when a file has been skipped with
+	// report.skip. Files depending on it are not processed, but the run does not fail
+	fileSkipped = errors.Errorf("file skipped")
 )
 
 type meta struct {
 	timeout            int
 	maxDependencyDepth int
 	files              []string
+	recursive          bool
+	vars               []string
+	varFiles           []string
 	noAutoInit         bool
+	parallelism        int
+	keepGoing          bool
+	reportJSON         string
+	reportJUnit        string
+	selection          loader.SelectOptions
+	workspace          string
+	lockTimeout        time.Duration
+
+	// noTerraform should be set by commands that never execute terraform. It will not
+	// create the terraform engine, and does not require terraform to be installed
+	noTerraform bool
 
 	Engine *terraform.Engine
 	Getter *getter.Client
@@ -38,6 +61,12 @@ type meta struct {
 
 	TauDir   string
 	CacheDir string
+
+	// command is name of the command walking files, used in reports
+	command string
+
+	// report is the result of last walk, it is created when walking files
+	report *walkReport
 }
 
 type initOptions struct {
@@ -55,6 +84,11 @@ func (m *meta) init(args []string) error {
 	m.TauDir = paths.JoinAndCreate(workingDir, paths.TauPath)
 	m.CacheDir = paths.JoinAndCreate(workingDir, paths.CachePath)
 
+	variables, err := m.variableValues()
+	if err != nil {
+		return err
+	}
+
 	{
 		timeout := time.Duration(m.timeout) * time.Second
 
@@ -73,6 +107,8 @@ func (m *meta) init(args []string) error {
 			CacheDirectory:   m.CacheDir,
 			MaxDepth:         m.maxDependencyDepth,
 			Getter:           m.Getter,
+			Recursive:        m.recursive,
+			Variables:        variables,
 		}
 
 		m.Loader = loader.New(options)
@@ -85,7 +121,7 @@ func (m *meta) init(args []string) error {
 		})
 	}
 
-	{
+	if !m.noTerraform {
 		m.Engine = terraform.NewEngine(&def.Options{
 			Runner: m.Runner,
 		})
@@ -103,9 +139,13 @@ func (m *meta) init(args []string) error {
 func (m *meta) addMetaFlags(cmd *cobra.Command) {
 	f := cmd.Flags()
 	f.IntVar(&m.timeout, "timeout", 10, "timeout for http client when retrieving sources")
-	f.StringArrayVarP(&m.files, "file", "f", []string{"."}, "file or directory to run configuration for")
+	f.StringArrayVarP(&m.files, "file", "f", []string{"."}, "file, directory or glob pattern to run configuration for")
+	f.BoolVarP(&m.recursive, "recursive", "r", false, "load files in all subdirectories of directories")
+	f.StringArrayVar(&m.vars, "var", nil, "set value of a variable declared in files, on the form NAME=VALUE")
+	f.StringArrayVar(&m.varFiles, "var-file", nil, "read values of variables from file")
 	f.BoolVar(&m.noAutoInit, "no-auto-init", false, "disable auto init")
 	f.IntVar(&m.maxDependencyDepth, "max-dependency-depth", 1, "defines max dependency depth when traversing dependencies") //nolint:lll
+	f.StringVar(&m.workspace, "workspace", "", "terraform workspace to use, overrides workspace in configuration")
 }
 
 // load wraps the Loader.Load function to load all files and return to caller.
@@ -119,54 +159,334 @@ func (m *meta) load() (loader.ParsedFileCollection, error) {
 		return nil, err
 	}
 
+	files, err = m.Loader.Select(files, &m.selection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.checkDeclaredVariables(files); err != nil {
+		return nil, err
+	}
+
+	if m.workspace != "" {
+		setWorkspace(files, m.workspace, map[*loader.ParsedFile]bool{})
+	}
+
 	for _, file := range files {
 		ui.Info("- Loaded %s", file)
 	}
 
 	if len(files) == 0 {
+		// nothing to do is not an error when only running on changed files
+		if m.selection.ChangedSince != "" {
+			ui.Info("- No files changed since %s", m.selection.ChangedSince)
+			return files, nil
+		}
+
 		return nil, noSourceInPath
 	}
 
 	return files, nil
 }
 
-// resolveDependencies resolves the dependencies for all files
-func (m *meta) resolveDependencies(file *loader.ParsedFile) (bool, error) {
+// variableValues returns the values of variables set by user. Values from environment
+// variables are overridden by values in variable files, that are overridden by --var arguments
+func (m *meta) variableValues() (map[string]cty.Value, error) {
+	values := config.EnvVariableValues(os.Environ())
+
+	for _, file := range m.varFiles {
+		fileValues, err := config.ParseVariableFile(paths.Abs(workingDir, file))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read variable file %s", file)
+		}
+
+		for name, value := range fileValues {
+			values[name] = value
+		}
+	}
+
+	for _, arg := range m.vars {
+		name, value, err := config.ParseVariableArg(arg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --var %s", arg)
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// checkDeclaredVariables checks that all variables set with --var are declared in at least
+// one of files. Values from environment and variable files can be shared between
+// configurations, so they are not checked
+func (m *meta) checkDeclaredVariables(files loader.ParsedFileCollection) error {
+	declared := map[string]bool{}
+	for _, file := range files {
+		for _, variable := range file.Config.Variables {
+			declared[variable.Name] = true
+		}
+	}
+
+	for _, arg := range m.vars {
+		name, _, err := config.ParseVariableArg(arg)
+		if err != nil {
+			return err
+		}
+
+		if !declared[name] {
+			return errors.Errorf("variable %s set with --var is not declared in any file", name)
+		}
+	}
+
+	return nil
+}
+
+// setWorkspace overrides workspace in files and all their dependencies, so dependencies
+// are read from same workspace as files are deployed to
+func setWorkspace(files loader.ParsedFileCollection, workspace string, visited map[*loader.ParsedFile]bool) {
+	for _, file := range files {
+		if visited[file] {
+			continue
+		}
+
+		visited[file] = true
+		file.Config.Workspace = workspace
+
+		deps := loader.ParsedFileCollection{}
+		for _, dep := range file.Dependencies {
+			deps = append(deps, dep)
+		}
+
+		setWorkspace(deps, workspace, visited)
+	}
+}
+
+// addWalkFlags adds the arguments that control how files are walked to command cmd.
+// Only commands that walk files using meta.walk or meta.walkReverse should add these flags.
+func (m *meta) addWalkFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.IntVar(&m.parallelism, "parallelism", 1, "number of independent modules to process in parallel")
+	f.BoolVar(&m.keepGoing, "keep-going", false, "continue processing modules that do not depend on a failed module")
+	f.StringVar(&m.reportJSON, "report-json", "", "write result of each module to file as json")
+	f.StringVar(&m.reportJUnit, "report-junit", "", "write result of each module to file as JUnit XML")
+	f.DurationVar(&m.lockTimeout, "lock-timeout", 0, "time to wait for other tau runs to release lock on a module")
+
+	m.command = cmd.Name()
+}
+
+// addSelectionFlags adds arguments to expand or restrict the files loaded to command cmd.
+// Selection is applied in meta.load, so only commands that load files should add them.
+func (m *meta) addSelectionFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.BoolVar(&m.selection.IncludeDependencies, "include-dependencies", false, "include all dependencies of selected files")
+	f.BoolVar(&m.selection.IncludeDependents, "include-dependents", false, "include files in working directory and subfolders that depend on selected files") //nolint:lll
+	f.StringArrayVar(&m.selection.Exclude, "exclude", []string{}, "file or directory to exclude from selected files")
+	f.StringVar(&m.selection.ChangedSince, "changed-since", "", "only select files changed since git revision")
+}
+
+// walk processes all files in dependency order. If parallelism is more than 1 it will
+// process independent files concurrently, and all output from a file is prefixed with
+// file name so it is possible to read the output.
+func (m *meta) walk(files loader.ParsedFileCollection, walkerFunc loader.WalkFunc) error {
+	return m.walkFiles(files, files.WalkParallel, walkerFunc)
+}
+
+// walkReverse processes all files in reverse dependency order, so files are processed
+// before the files they depend on. Otherwise same as walk.
+func (m *meta) walkReverse(files loader.ParsedFileCollection, walkerFunc loader.WalkFunc) error {
+	return m.walkFiles(files, files.WalkReverseParallel, walkerFunc)
+}
+
+// walkFiles walks files with walker and records the result of each file in report. By
+// default it stops processing new files after first error. With keep-going it processes
+// all files that do not depend on a failed file, and prints a report at the end.
+func (m *meta) walkFiles(
+	files loader.ParsedFileCollection,
+	walker func(int, loader.WalkFunc) error,
+	walkerFunc loader.WalkFunc,
+) error {
+	unlock, err := m.lockFiles(files)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m.prefixOutput(files)
+	m.report = newWalkReport()
+
+	err = walker(m.parallelism, func(file *loader.ParsedFile) error {
+		if !m.keepGoing && m.report.hasFailed() {
+			return nil
+		}
+
+		m.report.start(file)
+		defer m.report.finish(file)
+
+		if err := walkerFunc(file); err != nil {
+			if err != fileSkipped {
+				m.report.setStatus(file, walkFailed, err)
+			}
+
+			return err
+		}
+
+		return nil
+	})
+
+	// Only skipped files stopped the walk, so dependents have been skipped as well
+	if err != nil && !m.report.hasFailed() {
+		err = nil
+	}
+
+	reportErr := m.writeReports(files)
+
+	if m.keepGoing {
+		m.report.print(files)
+
+		if failed := m.report.filesWithStatus(files, walkFailed); len(failed) > 0 {
+			err = errors.Errorf("%v of %v file(s) failed", len(failed), len(files))
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return reportErr
+}
+
+// lockFiles takes the lock on all files, so other tau runs cannot process same files at
+// the same time. It waits up to lock timeout for each lock. Returned function releases all
+// locks, it has to be called when done processing files.
+func (m *meta) lockFiles(files loader.ParsedFileCollection) (func(), error) {
+	info := lock.NewInfo(lockCommand())
+	locks := []*lock.Lock{}
+
+	unlock := func() {
+		for _, l := range locks {
+			if err := l.Release(); err != nil {
+				ui.Warn("Could not release lock %s: %s", relativeToWorkingDir(l.Path), err)
+			}
+		}
+	}
+
+	for _, file := range files {
+		if m.lockTimeout > 0 {
+			ui.Debug("waiting up to %s for lock on %s", m.lockTimeout, file.Name)
+		}
+
+		l, err := lock.Acquire(file.LockFile(), info, m.lockTimeout)
+		if err != nil {
+			unlock()
+
+			if _, ok := err.(*lock.LockedError); ok {
+				return nil, errors.Errorf("%s is %s. Use --lock-timeout to wait for it, or run "+
+					"'tau unlock -f %s' if the lock is stale", file.Name, err, relativeToWorkingDir(file.FullPath))
+			}
+
+			return nil, err
+		}
+
+		locks = append(locks, l)
+	}
+
+	return unlock, nil
+}
+
+// lockCommand returns the command line recorded in locks
+func lockCommand() string {
+	return strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " ")
+}
+
+// writeReports writes the report of last walk to the files requested
+func (m *meta) writeReports(files loader.ParsedFileCollection) error {
+	if m.reportJSON != "" {
+		if err := m.report.writeJSON(paths.Abs(workingDir, m.reportJSON), m.command, files); err != nil {
+			return err
+		}
+	}
+
+	if m.reportJUnit != "" {
+		if err := m.report.writeJUnit(paths.Abs(workingDir, m.reportJUnit), m.command, files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prefixOutput prefixes all output from files with file name when processing files
+// in parallel
+func (m *meta) prefixOutput(files loader.ParsedFileCollection) {
+	if m.parallelism <= 1 {
+		return
+	}
+
+	ui.Debug("processing up to %v modules in parallel", m.parallelism)
+
+	for _, file := range files {
+		file.Log = ui.NewScope(file.Name)
+	}
+}
+
+// resolveDependencies resolves the dependencies for file and writes the input variables
+func (m *meta) resolveDependencies(file *loader.ParsedFile) (success bool, err error) {
 	if file.Config.Inputs == nil {
 		return true, nil
 	}
 
-	ui.Header("Resolving dependencies...")
+	success, err = m.resolveDependencyValues(file)
+	if err != nil || !success {
+		return false, err
+	}
 
-	success, err := m.Engine.ResolveDependencies(file)
-	if err != nil {
+	if err := m.Engine.WriteInputVariables(file); err != nil {
 		return false, err
 	}
 
-	if !success {
-		ui.NewLine()
-		ui.Info(color.GreenString("Some of the dependencies failed to resolve. This can be because dependency"))
-		ui.Info(color.GreenString("have not been applied yet, and therefore it cannot read remote-state."))
-		ui.NewLine()
+	return true, nil
+}
 
-		return false, nil
+// resolveDependencyValues resolves the dependencies for file and adds their values to the
+// context of file, without writing the input variables
+func (m *meta) resolveDependencyValues(file *loader.ParsedFile) (success bool, err error) {
+	if file.Config.Inputs == nil {
+		return true, nil
 	}
 
-	if err := m.Engine.WriteInputVariables(file); err != nil {
+	done := m.report.startPhase(file, phaseDependencies)
+	defer func() { done(err) }()
+
+	file.Log.Header("Resolving dependencies...")
+
+	success, err = m.Engine.ResolveDependencies(file)
+	if err != nil {
 		return false, err
 	}
 
+	if !success {
+		m.report.setStatus(file, walkUnresolved, nil)
+
+		file.Log.NewLine()
+		file.Log.Info(color.GreenString("Some of the dependencies failed to resolve. This can be because dependency"))
+		file.Log.Info(color.GreenString("have not been applied yet, and therefore it cannot read remote-state."))
+		file.Log.NewLine()
+
+		return false, nil
+	}
+
 	return true, nil
 }
 
 // autoInit can be called by any command to auto initialize the module
 func (m *meta) autoInit(file *loader.ParsedFile) error {
 	if file.IsInitialized() {
-		return nil
+		return m.selectWorkspace(file)
 	}
 
 	if m.noAutoInit {
-		ui.Debug("no-auto-init set, not initializing module")
+		file.Log.Debug("no-auto-init set, not initializing module")
 		return nil
 	}
 
@@ -174,12 +494,15 @@ func (m *meta) autoInit(file *loader.ParsedFile) error {
 }
 
 // runInit initializes the parsed file
-func (m *meta) runInit(file *loader.ParsedFile, options *initOptions) error {
+func (m *meta) runInit(file *loader.ParsedFile, options *initOptions) (err error) {
+	done := m.report.startPhase(file, phaseInit)
+	defer func() { done(err) }()
+
 	if options == nil {
 		options = &initOptions{}
 	}
 
-	ui.Header("Initializing tau...")
+	file.Log.Header("Initializing tau...")
 
 	// Loading module
 
@@ -191,9 +514,9 @@ func (m *meta) runInit(file *loader.ParsedFile, options *initOptions) error {
 		}
 
 		if module.Version != "" {
-			ui.Info("- Loading module from terraform registry %s, version %s", module.Source, module.Version)
+			file.Log.Info("- Loading module from terraform registry %s, version %s", module.Source, module.Version)
 		} else {
-			ui.Info("- Loading module from %s", module.Source)
+			file.Log.Info("- Loading module from %s", module.Source)
 		}
 
 		if err := m.Getter.Get(module.GetSource(), file.ModuleDir()); err != nil {
@@ -204,7 +527,7 @@ func (m *meta) runInit(file *loader.ParsedFile, options *initOptions) error {
 	// Creating overrides
 
 	if !options.noOverrides {
-		ui.Info("- Creating overrides for backend")
+		file.Log.Info("- Creating overrides for backend")
 
 		if err := m.Engine.CreateOverrides(file); err != nil {
 			return err
@@ -213,14 +536,14 @@ func (m *meta) runInit(file *loader.ParsedFile, options *initOptions) error {
 
 	// Executing terraform command
 
-	ui.NewLine()
-	ui.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
-	ui.NewLine()
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
 
 	shellOptions := &shell.Options{
 		WorkingDirectory: file.ModuleDir(),
-		Stdout:           shell.Processors(processors.NewUI(ui.Info)),
-		Stderr:           shell.Processors(processors.NewUI(ui.Error)),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
 		Env:              file.Env,
 	}
 
@@ -234,5 +557,46 @@ func (m *meta) runInit(file *loader.ParsedFile, options *initOptions) error {
 		return err
 	}
 
-	return nil
+	return m.selectWorkspace(file)
+}
+
+// selectWorkspace selects, or creates, the terraform workspace configured for file. Current
+// workspace is not changed if file does not configure a workspace.
+func (m *meta) selectWorkspace(file *loader.ParsedFile) error {
+	workspace := file.Config.Workspace
+	if workspace == "" {
+		return nil
+	}
+
+	file.Log.Debug("selecting workspace %s", workspace)
+
+	options := &shell.Options{
+		WorkingDirectory: file.ModuleDir(),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
+		Env:              file.Env,
+	}
+
+	return m.Engine.Executor.SelectWorkspace(options, workspace)
+}
+
+// runHooks runs the hooks for event and command on file, and records the time used in
+// report. Event is used as phase name.
+func (m *meta) runHooks(file *loader.ParsedFile, event, command string) error {
+	done := m.report.startPhase(file, event)
+
+	err := m.Runner.Run(file, event, command)
+	done(err)
+
+	return err
+}
+
+// execute executes the terraform command for file, and records the time used in report
+func (m *meta) execute(file *loader.ParsedFile, options *shell.Options, command string, args ...string) error {
+	done := m.report.startPhase(file, phaseCommand)
+
+	err := m.Engine.Executor.Execute(options, command, args...)
+	done(err)
+
+	return err
 }
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/paths"
+	"github.com/avinor/tau/pkg/helpers/ui"
+	"github.com/avinor/tau/pkg/scaffold"
+)
+
+type newCmd struct {
+	meta
+
+	version string
+	output  string
+	force   bool
+}
+
+var (
+	// newFileExists is returned if output file exists and force is not set
+	newFileExists = errors.Errorf("file already exists, use --force to overwrite")
+
+	// newLong is long description of new command
+	newLong = templates.LongDesc(`Create a new tau file for a module. It downloads the module
+		and reads the variables it declares, then writes a file with a module block and an
+		inputs block. Required variables are added with an empty value that has to be set,
+		optional variables are added as comments with their default value. Descriptions and
+		types are added as comments.
+
+		If an _auto file in same folder as new file defines a backend it is inherited,
+		otherwise a backend block has to be added.
+
+		File is written to --output, or a file named after the module in current folder.
+		`)
+
+	// newExample is examples for new command
+	newExample = templates.Examples(`
+		# Create kubernetes.hcl from terraform registry module
+		tau new avinor/kubernetes/azurerm --version 1.0.0
+
+		# Create a file for a local module in another folder
+		tau new ./modules/vnet -o prod/vnet.hcl
+	`)
+)
+
+// newNewCmd creates a new new command
+func newNewCmd() *cobra.Command {
+	nc := &newCmd{
+		meta: meta{
+			noTerraform: true,
+		},
+	}
+
+	newCmd := &cobra.Command{
+		Use:                   "new SOURCE [--version VERSION] [-o FILE]",
+		Short:                 "Create a new tau file from a module",
+		Long:                  newLong,
+		Example:               newExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := nc.meta.init(args); err != nil {
+				return err
+			}
+
+			return nc.run(args)
+		},
+	}
+
+	f := newCmd.Flags()
+	f.StringVar(&nc.version, "version", "", "version of module, if it is from terraform registry")
+	f.StringVarP(&nc.output, "output", "o", "", "file to write (default is module name in current folder)")
+	f.BoolVar(&nc.force, "force", false, "overwrite file if it exists")
+	f.IntVar(&nc.timeout, "timeout", 10, "timeout for http client when retrieving sources")
+
+	return newCmd
+}
+
+func (nc *newCmd) run(args []string) error {
+	module := &config.Module{
+		Source:  args[0],
+		Version: nc.version,
+	}
+
+	output := nc.output
+	if output == "" {
+		output = scaffold.ModuleName(module.Source, module.Version) + ".hcl"
+	}
+	output = paths.Abs(workingDir, output)
+
+	if paths.IsFile(output) && !nc.force {
+		return newFileExists
+	}
+
+	variables, err := nc.moduleVariables(module)
+	if err != nil {
+		return err
+	}
+
+	backendFile, err := backendAutoFile(filepath.Dir(output))
+	if err != nil {
+		return err
+	}
+
+	content := scaffold.Generate(&scaffold.Options{
+		Source:      module.Source,
+		Version:     module.Version,
+		Variables:   variables,
+		BackendFile: backendFile,
+	})
+
+	paths.EnsureDirectoryExists(filepath.Dir(output))
+
+	if err := ioutil.WriteFile(output, content, os.ModePerm); err != nil {
+		return err
+	}
+
+	ui.NewLine()
+	ui.Info("Created %s with %v variable(s)", relativeToWorkingDir(output), len(variables))
+	ui.NewLine()
+
+	return nil
+}
+
+// moduleVariables downloads module to a temporary directory and returns the variables
+// it declares
+func (nc *newCmd) moduleVariables(module *config.Module) ([]*scaffold.Variable, error) {
+	tempDir, err := ioutil.TempDir(nc.CacheDir, "new")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	dst := filepath.Join(tempDir, "module")
+
+	ui.Header("Loading module...")
+
+	if module.Version != "" {
+		ui.Info("- Loading module from terraform registry %s, version %s", module.Source, module.Version)
+	} else {
+		ui.Info("- Loading module from %s", module.Source)
+	}
+
+	if err := nc.Getter.Get(module.GetSource(), dst); err != nil {
+		return nil, err
+	}
+
+	variables, err := scaffold.ParseVariables(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(variables) == 0 {
+		ui.Warn("Module does not declare any variables")
+	}
+
+	return variables, nil
+}
+
+// backendAutoFile returns name of the auto import file in dir that defines backend, or an
+// empty string if no auto import file defines it
+func backendAutoFile(dir string) (string, error) {
+	if !paths.IsDir(dir) {
+		return "", nil
+	}
+
+	autoFiles, err := loader.AutoImports(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, autoFile := range autoFiles {
+		cfg, err := autoFile.Config()
+		if err != nil {
+			return "", err
+		}
+
+		if cfg.Backend != nil {
+			return filepath.Base(autoFile.FullPath), nil
+		}
+	}
+
+	return "", nil
+}
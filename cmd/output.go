@@ -80,6 +80,8 @@ func newOutputCmd() *cobra.Command {
 	f.StringVarP(&oc.output, "output", "o", "plain", "output format of variables")
 
 	oc.addMetaFlags(outputCmd)
+	oc.addSelectionFlags(outputCmd)
+	oc.addWalkFlags(outputCmd)
 
 	return outputCmd
 }
@@ -126,7 +128,7 @@ func (oc *outputCmd) run(args []string) error {
 		}
 	}
 
-	if err := files.Walk(oc.runFile); err != nil {
+	if err := oc.walk(files, oc.runFile); err != nil {
 		return err
 	}
 
@@ -136,17 +138,19 @@ func (oc *outputCmd) run(args []string) error {
 }
 
 func (oc *outputCmd) runFile(file *loader.ParsedFile) error {
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
 
 	// Running prepare hook
 
-	ui.Header("Executing prepare hooks...")
+	file.Log.Header("Executing prepare hooks...")
 
-	if err := oc.Runner.Run(file, "prepare", "output"); err != nil {
+	if err := oc.runHooks(file, "prepare", "output"); err != nil {
 		return err
 	}
 
-	oc.autoInit(file)
+	if err := oc.autoInit(file); err != nil {
+		return err
+	}
 
 	// Resolving dependencies
 
@@ -163,21 +167,21 @@ func (oc *outputCmd) runFile(file *loader.ParsedFile) error {
 
 	// Executing terraform command
 
-	ui.NewLine()
-	ui.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
-	ui.NewLine()
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
 
 	outputProcessor := oc.Engine.Executor.NewOutputProcessor()
 
 	options := &shell.Options{
 		WorkingDirectory: file.ModuleDir(),
 		Stdout:           shell.Processors(outputProcessor),
-		Stderr:           shell.Processors(processors.NewUI(ui.Error)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
 		Env:              file.Env,
 	}
 
 	if !oc.shouldProcessOutput() {
-		options.Stdout = append(options.Stdout, processors.NewUI(ui.Info))
+		options.Stdout = append(options.Stdout, processors.NewUI(file.Log.Info))
 	}
 
 	extraArgs := getExtraArgs(oc.Engine.Compatibility.GetInvalidArgs("output")...)
@@ -186,7 +190,7 @@ func (oc *outputCmd) runFile(file *loader.ParsedFile) error {
 		extraArgs = append(extraArgs, "-json")
 	}
 
-	if err := oc.Engine.Executor.Execute(options, "output", extraArgs...); err != nil {
+	if err := oc.execute(file, options, "output", extraArgs...); err != nil {
 		return err
 	}
 
@@ -203,15 +207,15 @@ func (oc *outputCmd) runFile(file *loader.ParsedFile) error {
 
 	// Executing finish hook
 
-	ui.Header("Executing finish hooks...")
+	file.Log.Header("Executing finish hooks...")
 
-	if err := oc.Runner.Run(file, "finish", "output"); err != nil {
+	if err := oc.runHooks(file, "finish", "output"); err != nil {
 		return err
 	}
 
 	// Printing output
 
-	ui.NewLine()
+	file.Log.NewLine()
 
 	switch oc.output {
 	case "json":
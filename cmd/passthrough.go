@@ -80,6 +80,13 @@ var (
 			SingleResource:   true,
 			MaximumNArgs:     1,
 		},
+		"workspace": {
+			Use:              "workspace -f SOURCE [ARGS...]",
+			ShortDescription: "Workspace management",
+			LongDescription:  "Workspace management",
+			SingleResource:   true,
+			MaximumNArgs:     2,
+		},
 	}
 )
 
@@ -132,6 +139,12 @@ func (pt *ptCmd) run(args []string) error {
 		}
 	}
 
+	unlock, err := pt.lockFiles(files)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if err := files.Walk(func(file *loader.ParsedFile) error {
 		return pt.runFile(file, args)
 	}); err != nil {
@@ -144,45 +157,47 @@ func (pt *ptCmd) run(args []string) error {
 }
 
 func (pt *ptCmd) runFile(file *loader.ParsedFile, args []string) error {
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
 
 	// Running prepare hook
 
-	ui.Header("Executing prepare hooks...")
+	file.Log.Header("Executing prepare hooks...")
 
-	if err := pt.Runner.Run(file, "prepare", pt.name); err != nil {
+	if err := pt.runHooks(file, "prepare", pt.name); err != nil {
 		return err
 	}
 
-	pt.autoInit(file)
+	if err := pt.autoInit(file); err != nil {
+		return err
+	}
 
 	// Executing terraform command
 
-	ui.NewLine()
-	ui.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
-	ui.NewLine()
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
 
 	options := &shell.Options{
 		WorkingDirectory: file.ModuleDir(),
-		Stdout:           shell.Processors(processors.NewUI(ui.Info)),
-		Stderr:           shell.Processors(processors.NewUI(ui.Error)),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
 		Env:              file.Env,
 	}
 
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
 
 	extraArgs := getExtraArgs(pt.Engine.Compatibility.GetInvalidArgs(pt.name)...)
 	extraArgs = append(extraArgs, pt.command.AdditionalArgs...)
 	extraArgs = append(extraArgs, args...)
-	if err := pt.Engine.Executor.Execute(options, pt.name, extraArgs...); err != nil {
+	if err := pt.execute(file, options, pt.name, extraArgs...); err != nil {
 		return err
 	}
 
 	// Executing finish hook
 
-	ui.Header("Executing finish hooks...")
+	file.Log.Header("Executing finish hooks...")
 
-	if err := pt.Runner.Run(file, "finish", pt.name); err != nil {
+	if err := pt.runHooks(file, "finish", pt.name); err != nil {
 		return err
 	}
 
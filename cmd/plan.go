@@ -1,32 +1,87 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
 
 	"github.com/fatih/color"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/bundle"
 	"github.com/avinor/tau/pkg/config/loader"
 	"github.com/avinor/tau/pkg/helpers/paths"
 	"github.com/avinor/tau/pkg/helpers/ui"
 	"github.com/avinor/tau/pkg/shell"
 	"github.com/avinor/tau/pkg/shell/processors"
+	"github.com/avinor/tau/pkg/terraform/def"
 )
 
 type planCmd struct {
 	meta
 
-	destroy bool
+	destroy          bool
+	detailedExitCode bool
+	bundle           string
+
+	// hasChanges is set if terraform returned exit code 2 for any file when using
+	// detailed exit code. Planned is set for each file a plan was created for in this
+	// run. Summaries, planned and hasChanges are guarded by lock
+	hasChanges bool
+	summaries  map[*loader.ParsedFile]*def.PlanSummary
+	planned    map[*loader.ParsedFile]bool
+	lock       sync.Mutex
+}
+
+// planSummaryFile is the content of plan summary file written after plan
+type planSummaryFile struct {
+	Deployments []*planDeployment `json:"deployments"`
+	Total       *def.PlanSummary  `json:"total"`
+}
+
+// planDeployment is the plan summary for a single deployment. Changes is nil if
+// no plan was created, for instance if dependencies could not be resolved. Error is
+// set if planning the deployment failed
+type planDeployment struct {
+	Name       string           `json:"name"`
+	Path       string           `json:"path"`
+	Planned    bool             `json:"planned"`
+	HasDestroy bool             `json:"has_destroy"`
+	Changes    *def.PlanSummary `json:"changes"`
+	Error      string           `json:"error,omitempty"`
 }
 
 var (
+	// bundleNoPlans is returned when writing a bundle and no plans were created
+	bundleNoPlans = errors.Errorf("no plans were created, bundle not written")
+
 	// planLong is long description of plan command
 	planLong = templates.LongDesc(`Generate and show an execution plan where its possible.
 		Command will resolve dependencies, create input variables and run terraform plan.
 		For some dependencies it will not be possible if resources it depends on have not
 		been deployed yet. It will not be able to show a plan, but apply will be able to
 		apply the resources. 
+
+		When all files have been planned it prints a summary of the changes in each
+		deployment, and highlights deployments that will destroy resources. The summary
+		is also written to .tau/plan-summary.json. Summary is also written when planning
+		some of the deployments failed, with the error of each. No bundle is written then.
+
+		With --detailed-exitcode tau exits with 0 if no deployment has changes, 1 on
+		errors and 2 if any of the deployments have changes.
+
+		Use --bundle to write everything apply needs for each planned deployment to a
+		single archive. That is the module, overrides, input variables and plan, with a
+		manifest of file hashes. It can be applied later, on another machine, with
+		tau apply --bundle without downloading modules or resolving dependencies again.
 		`)
 
 	// planExample is examples for plan command
@@ -36,12 +91,21 @@ var (
 
 		# Plan a single module
 		tau plan -f module.hcl
+
+		# Plan current folder and exit with code 2 if there are any changes
+		tau plan --detailed-exitcode
+
+		# Plan current folder and write plans to a bundle
+		tau plan --bundle plan.tar.gz
 	`)
 )
 
 // newPlanCmd creates a new plan command
 func newPlanCmd() *cobra.Command {
-	pc := &planCmd{}
+	pc := &planCmd{
+		summaries: map[*loader.ParsedFile]*def.PlanSummary{},
+		planned:   map[*loader.ParsedFile]bool{},
+	}
 
 	planCmd := &cobra.Command{
 		Use:                   "plan [-f SORUCE]",
@@ -63,8 +127,12 @@ func newPlanCmd() *cobra.Command {
 
 	f := planCmd.Flags()
 	f.BoolVar(&pc.destroy, "destroy", false, "create plan to destroy resources")
+	f.BoolVar(&pc.detailedExitCode, "detailed-exitcode", false, "return exit code 2 if any deployment has changes")
+	f.StringVar(&pc.bundle, "bundle", "", "write plans and everything needed to apply them to bundle file")
 
 	pc.addMetaFlags(planCmd)
+	pc.addSelectionFlags(planCmd)
+	pc.addWalkFlags(planCmd)
 
 	return planCmd
 }
@@ -83,27 +151,51 @@ func (pc *planCmd) run(args []string) error {
 		}
 	}
 
-	if err := files.Walk(pc.runFile); err != nil {
+	// summary is written also when some files failed, so failed deployments are reported
+	walkErr := pc.walk(files, pc.runFile)
+
+	summary := pc.newSummaryFile(files)
+
+	pc.printSummary(summary)
+
+	if err := pc.writeSummary(summary); err != nil {
 		return err
 	}
 
+	if walkErr != nil {
+		ui.NewLine()
+		return walkErr
+	}
+
+	if pc.bundle != "" {
+		if err := pc.writeBundle(files); err != nil {
+			return err
+		}
+	}
+
 	ui.NewLine()
 
+	if pc.detailedExitCode && pc.hasChanges {
+		return &ExitCodeError{Code: 2}
+	}
+
 	return nil
 }
 
 func (pc *planCmd) runFile(file *loader.ParsedFile) error {
-	ui.Separator(file.Name)
+	file.Log.Separator(file.Name)
 
 	// Running prepare hook
 
-	ui.Header("Executing prepare hooks...")
+	file.Log.Header("Executing prepare hooks...")
 
-	if err := pc.Runner.Run(file, "prepare", "plan"); err != nil {
+	if err := pc.runHooks(file, "prepare", "plan"); err != nil {
 		return err
 	}
 
-	pc.autoInit(file)
+	if err := pc.autoInit(file); err != nil {
+		return err
+	}
 
 	// Resolving dependencies
 
@@ -118,19 +210,19 @@ func (pc *planCmd) runFile(file *loader.ParsedFile) error {
 
 	// Executing terraform command
 
-	ui.NewLine()
-	ui.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
-	ui.NewLine()
+	file.Log.NewLine()
+	file.Log.Info(color.New(color.FgGreen, color.Bold).Sprint("Tau has been successfully initialized!"))
+	file.Log.NewLine()
 
 	if !paths.IsFile(file.VariableFile()) {
-		ui.Warn("Cannot create a plan for %s", file.Name)
+		file.Log.Warn("Cannot create a plan for %s", file.Name)
 		return nil
 	}
 
 	options := &shell.Options{
 		WorkingDirectory: file.ModuleDir(),
-		Stdout:           shell.Processors(processors.NewUI(ui.Info)),
-		Stderr:           shell.Processors(processors.NewUI(ui.Error)),
+		Stdout:           shell.Processors(processors.NewUI(file.Log.Info)),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
 		Env:              file.Env,
 	}
 
@@ -141,17 +233,221 @@ func (pc *planCmd) runFile(file *loader.ParsedFile) error {
 		extraArgs = append(extraArgs, "-destroy")
 	}
 
-	if err := pc.Engine.Executor.Execute(options, "plan", extraArgs...); err != nil {
+	if pc.detailedExitCode {
+		extraArgs = append(extraArgs, "-detailed-exitcode")
+	}
+
+	done := pc.report.startPhase(file, phaseCommand)
+
+	err = pc.Engine.Executor.Execute(options, "plan", extraArgs...)
+
+	// exit code 2 means that plan succeeded and there are changes
+	if exitErr, ok := err.(*shell.ExitError); ok && pc.detailedExitCode && exitErr.ExitCode == 2 {
+		pc.setHasChanges()
+		err = nil
+	}
+
+	done(err)
+
+	if err != nil {
 		return err
 	}
 
+	if err := file.WriteFingerprint(); err != nil {
+		file.Log.Warn("Could not write fingerprint of plan: %s", err)
+	}
+
+	pc.setPlanned(file)
+	pc.summarizePlan(file)
+
 	// Executing finish hook
 
-	ui.Header("Executing finish hooks...")
+	file.Log.Header("Executing finish hooks...")
+
+	if err := pc.runHooks(file, "finish", "plan"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// summarizePlan reads the plan file for file and saves the summary. Plan has already
+// been created at this point, so failing to read it is only reported as a warning
+func (pc *planCmd) summarizePlan(file *loader.ParsedFile) {
+	options := &shell.Options{
+		WorkingDirectory: file.ModuleDir(),
+		Stderr:           shell.Processors(processors.NewUI(file.Log.Error)),
+		Env:              file.Env,
+	}
+
+	summary, err := pc.Engine.Executor.SummarizePlan(options, file.PlanFile())
+	if err != nil {
+		file.Log.Warn("Could not read plan for summary: %s", err)
+		return
+	}
+
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	pc.summaries[file] = summary
+}
+
+// setPlanned marks that a plan was created for file
+func (pc *planCmd) setPlanned(file *loader.ParsedFile) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	pc.planned[file] = true
+}
+
+// writeBundle writes the module directory of all files that were planned to bundle file.
+// Files without a plan are not included in bundle
+func (pc *planCmd) writeBundle(files loader.ParsedFileCollection) error {
+	ui.Header("Writing bundle...")
+
+	deployments := []*bundle.Deployment{}
+
+	for _, file := range files {
+		if !pc.planned[file] {
+			ui.Warn("- No plan for %s, it is not added to bundle", file.Name)
+			continue
+		}
+
+		dir, err := filepath.Rel(pc.TauDir, file.ModuleDir())
+		if err != nil {
+			return err
+		}
 
-	if err := pc.Runner.Run(file, "finish", "plan"); err != nil {
+		deployments = append(deployments, &bundle.Deployment{
+			Name: file.Name,
+			Dir:  dir,
+		})
+	}
+
+	if len(deployments) == 0 {
+		return bundleNoPlans
+	}
+
+	output := paths.Abs(workingDir, pc.bundle)
+	paths.EnsureDirectoryExists(filepath.Dir(output))
+
+	manifest, err := bundle.Create(output, pc.TauDir, BuildTag, deployments)
+	if err != nil {
 		return err
 	}
 
+	for _, deployment := range manifest.Deployments {
+		ui.Info("- Added %s (%v files)", deployment.Name, len(deployment.Files))
+	}
+
+	ui.Info("- Wrote %s", relativeToWorkingDir(output))
+
 	return nil
 }
+
+// setHasChanges marks that at least one of the files have changes
+func (pc *planCmd) setHasChanges() {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	pc.hasChanges = true
+}
+
+// newSummaryFile creates the plan summary for all files, in same order as they were
+// loaded, and the total number of changes
+func (pc *planCmd) newSummaryFile(files loader.ParsedFileCollection) *planSummaryFile {
+	summary := &planSummaryFile{
+		Deployments: []*planDeployment{},
+		Total:       &def.PlanSummary{},
+	}
+
+	for _, file := range files {
+		deployment := &planDeployment{
+			Name: file.Name,
+			Path: relativeToWorkingDir(file.FullPath),
+		}
+
+		if changes, ok := pc.summaries[file]; ok {
+			deployment.Planned = true
+			deployment.HasDestroy = changes.HasDestroy()
+			deployment.Changes = changes
+
+			summary.Total.Add += changes.Add
+			summary.Total.Change += changes.Change
+			summary.Total.Replace += changes.Replace
+			summary.Total.Destroy += changes.Destroy
+		}
+
+		if result := pc.report.result(file); result.Status == walkFailed {
+			deployment.Error = "failed"
+			if result.Err != nil {
+				deployment.Error = result.Err.Error()
+			}
+		}
+
+		summary.Deployments = append(summary.Deployments, deployment)
+	}
+
+	return summary
+}
+
+// printSummary prints a table with number of changes in each deployment. Deployments
+// that destroy resources are highlighted
+func (pc *planCmd) printSummary(summary *planSummaryFile) {
+	var buffer bytes.Buffer
+
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, "DEPLOYMENT\tADD\tCHANGE\tREPLACE\tDESTROY\t")
+
+	for _, deployment := range summary.Deployments {
+		if !deployment.Planned {
+			note := "(no plan)"
+			if deployment.Error != "" {
+				note = "(failed)"
+			}
+
+			fmt.Fprintf(writer, "%s\t-\t-\t-\t-\t%s\n", deployment.Name, note)
+			continue
+		}
+
+		changes := deployment.Changes
+		note := ""
+		if deployment.HasDestroy {
+			note = "(destroys resources)"
+		}
+
+		fmt.Fprintf(writer, "%s\t%v\t%v\t%v\t%v\t%s\n",
+			deployment.Name, changes.Add, changes.Change, changes.Replace, changes.Destroy, note)
+	}
+
+	total := summary.Total
+	fmt.Fprintf(writer, "TOTAL\t%v\t%v\t%v\t%v\t\n", total.Add, total.Change, total.Replace, total.Destroy)
+
+	writer.Flush()
+
+	ui.Separator("Plan summary")
+
+	lines := strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n")
+	for idx, line := range lines {
+		line = strings.TrimRight(line, " ")
+
+		switch {
+		case idx == 0 || idx == len(lines)-1:
+			ui.Info("%s", color.New(color.Bold).Sprint(line))
+		case idx <= len(summary.Deployments) && (summary.Deployments[idx-1].HasDestroy || summary.Deployments[idx-1].Error != ""):
+			ui.Info("%s", color.RedString(line))
+		default:
+			ui.Info("%s", line)
+		}
+	}
+}
+
+// writeSummary writes the summary as json to plan summary file in tau directory
+func (pc *planCmd) writeSummary(summary *planSummaryFile) error {
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(paths.Join(pc.TauDir, "plan-summary.json"), content, os.ModePerm)
+}
@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/paths"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+type renderCmd struct {
+	meta
+
+	outputDir string
+	redact    bool
+}
+
+// renderedFile is a file generated by tau, path is relative to the tau directory of file
+type renderedFile struct {
+	Path    string
+	Content []byte
+}
+
+var (
+	// redactedValue replaces all string values when redacting
+	redactedValue = cty.StringVal("(redacted)")
+
+	// renderLong is long description of render command
+	renderLong = templates.LongDesc(`Render the files tau generates for terraform without running
+		plan. That is the input variables (terraform.tfvars), backend overrides (tau_override.tf)
+		and the terraform code used to resolve each dependency (dep/NAME/main.tf).
+
+		Dependencies have to be resolved to render input variables, so it requires terraform
+		and access to remote state, same as plan. If dependencies cannot be resolved the input
+		variables are not rendered.
+
+		When a file includes other files, or has auto imports, the files it is merged from
+		are listed in the order they are merged before the rendered files.
+
+		Files are printed to stdout, or written to --output-dir using same layout as in .tau
+		folder. Use --redact to replace all string values, so rendered files can be shared
+		and compared between branches without exposing secrets.
+		`)
+
+	// renderExample is examples for render command
+	renderExample = templates.Examples(`
+		# Print all generated files for module.hcl
+		tau render -f module.hcl
+
+		# Write generated files for all files in folder, without string values
+		tau render --redact --output-dir rendered
+	`)
+)
+
+// newRenderCmd creates a new render command
+func newRenderCmd() *cobra.Command {
+	rc := &renderCmd{}
+
+	renderCmd := &cobra.Command{
+		Use:                   "render [-f SOURCE]",
+		Short:                 "Render files generated for terraform",
+		Long:                  renderLong,
+		Example:               renderExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := rc.meta.init(args); err != nil {
+				return err
+			}
+
+			return rc.run(args)
+		},
+	}
+
+	f := renderCmd.Flags()
+	f.StringVar(&rc.outputDir, "output-dir", "", "write rendered files to directory instead of stdout")
+	f.BoolVar(&rc.redact, "redact", false, "replace all string values in rendered files")
+	f.DurationVar(&rc.lockTimeout, "lock-timeout", 0, "time to wait for other tau runs to release lock on a module")
+
+	rc.addMetaFlags(renderCmd)
+	rc.addSelectionFlags(renderCmd)
+
+	return renderCmd
+}
+
+func (rc *renderCmd) run(args []string) error {
+	// load all sources
+	files, err := rc.load()
+	if err != nil {
+		return err
+	}
+
+	// Resolving dependencies writes to tau directory of file
+	unlock, err := rc.lockFiles(files)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, file := range files {
+		rendered, err := rc.renderFile(file)
+		if err != nil {
+			return err
+		}
+
+		if err := rc.writeFiles(file, rendered); err != nil {
+			return err
+		}
+	}
+
+	ui.NewLine()
+
+	return nil
+}
+
+// renderFile generates all files for file, without writing them to disk
+func (rc *renderCmd) renderFile(file *loader.ParsedFile) ([]*renderedFile, error) {
+	file.Log.Separator(file.Name)
+
+	if len(file.Children()) > 0 {
+		file.Log.Info("Merged from %s (later files take precedence)", strings.Join(file.MergeChain(), " < "))
+	}
+
+	rendered := []*renderedFile{}
+
+	overrides, create, err := rc.Engine.Generator.GenerateOverrides(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if create {
+		rendered = append(rendered, &renderedFile{"tau_override.tf", overrides})
+	}
+
+	if file.Config.Inputs == nil {
+		return rendered, nil
+	}
+
+	processors, _, err := rc.Engine.Generator.GenerateDependencies(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, processor := range processors {
+		rendered = append(rendered, &renderedFile{
+			Path:    filepath.Join("dep", processor.Name(), "main.tf"),
+			Content: processor.Content(),
+		})
+	}
+
+	// Resolving dependencies
+
+	file.Log.Header("Executing prepare hooks...")
+
+	if err := rc.Runner.Run(file, "prepare", "render"); err != nil {
+		return nil, err
+	}
+
+	file.Log.Header("Resolving dependencies...")
+
+	success, err := rc.Engine.ResolveDependencies(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !success {
+		file.Log.Warn("Some of the dependencies failed to resolve, cannot render input variables")
+		return rendered, nil
+	}
+
+	variables, err := rc.Engine.Generator.GenerateVariables(file)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered = append(rendered, &renderedFile{"terraform.tfvars", variables})
+
+	return rendered, nil
+}
+
+// writeFiles prints the rendered files, or writes them to output directory. Files are
+// sorted by path so output can be compared
+func (rc *renderCmd) writeFiles(file *loader.ParsedFile, rendered []*renderedFile) error {
+	sort.Slice(rendered, func(i, j int) bool {
+		return rendered[i].Path < rendered[j].Path
+	})
+
+	for _, r := range rendered {
+		content := r.Content
+
+		if rc.redact {
+			redacted, err := redactHCL(content, r.Path)
+			if err != nil {
+				return err
+			}
+
+			content = redacted
+		}
+
+		path := filepath.Join(file.Name, r.Path)
+
+		if rc.outputDir == "" {
+			ui.Output("### %s", filepath.ToSlash(path))
+			ui.Output("%s", strings.TrimRight(string(content), "\n"))
+			ui.Output("")
+			continue
+		}
+
+		dest := filepath.Join(paths.Abs(workingDir, rc.outputDir), path)
+		paths.EnsureDirectoryExists(filepath.Dir(dest))
+
+		file.Log.Info("- Writing %s", relativeToWorkingDir(dest))
+
+		if err := ioutil.WriteFile(dest, content, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// redactHCL replaces all string values in attributes of content that can be evaluated
+// without any variables. Attributes referencing other values are not changed, so it is
+// still possible to see which dependency outputs are used.
+func redactHCL(content []byte, filename string) ([]byte, error) {
+	f, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	redactBody(f.Body())
+
+	return f.Bytes(), nil
+}
+
+// redactBody redacts all attributes in body and nested blocks
+func redactBody(body *hclwrite.Body) {
+	for name, attr := range body.Attributes() {
+		tokens := attr.Expr().BuildTokens(nil).Bytes()
+
+		expr, diags := hclsyntax.ParseExpression(tokens, "", hcl.InitialPos)
+		if diags.HasErrors() {
+			continue
+		}
+
+		value, diags := expr.Value(nil)
+		if diags.HasErrors() {
+			continue
+		}
+
+		body.SetAttributeValue(name, redactValue(value))
+	}
+
+	for _, block := range body.Blocks() {
+		redactBody(block.Body())
+	}
+}
+
+// redactValue returns value with all strings, also nested in collections, replaced
+func redactValue(value cty.Value) cty.Value {
+	if value.IsNull() || !value.IsKnown() {
+		return value
+	}
+
+	ty := value.Type()
+
+	switch {
+	case ty == cty.String:
+		return redactedValue
+	case ty.IsObjectType() || ty.IsMapType():
+		values := map[string]cty.Value{}
+		for k, v := range value.AsValueMap() {
+			values[k] = redactValue(v)
+		}
+
+		if len(values) == 0 {
+			return value
+		}
+
+		if ty.IsMapType() {
+			return cty.MapVal(values)
+		}
+
+		return cty.ObjectVal(values)
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		values := []cty.Value{}
+		for _, v := range value.AsValueSlice() {
+			values = append(values, redactValue(v))
+		}
+
+		if len(values) == 0 {
+			return value
+		}
+
+		switch {
+		case ty.IsTupleType():
+			return cty.TupleVal(values)
+		case ty.IsSetType():
+			return cty.SetVal(values)
+		}
+
+		return cty.ListVal(values)
+	}
+
+	return value
+}
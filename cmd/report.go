@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+// walkStatus is the status of a file after walking all files
+type walkStatus string
+
+const (
+	walkSucceeded  walkStatus = "succeeded"
+	walkFailed     walkStatus = "failed"
+	walkSkipped    walkStatus = "skipped"
+	walkUnresolved walkStatus = "unresolved"
+)
+
+// Phases of processing a file that are timed in report
+const (
+	phasePrepare      = "prepare"
+	phaseInit         = "init"
+	phaseDependencies = "dependencies"
+	phaseCommand      = "command"
+	phaseFinish       = "finish"
+)
+
+// walkResult is the result of processing a single file. Reason is set when a command
+// decides to skip a file, otherwise reason for skipping is found from dependencies
+type walkResult struct {
+	Status   walkStatus
+	Err      error
+	Reason   string
+	Started  time.Time
+	Duration time.Duration
+	Phases   []*phaseResult
+}
+
+// phaseResult is the result of a single phase when processing a file
+type phaseResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// walkReport collects the result for each file when walking files. It is safe to use
+// from multiple goroutines. All methods can be called on a nil report, which does nothing.
+type walkReport struct {
+	lock    sync.Mutex
+	results map[*loader.ParsedFile]*walkResult
+}
+
+// newWalkReport creates a new empty report
+func newWalkReport() *walkReport {
+	return &walkReport{
+		results: map[*loader.ParsedFile]*walkResult{},
+	}
+}
+
+// start marks that processing of file has started
+func (r *walkReport) start(file *loader.ParsedFile) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.results[file] = &walkResult{Started: time.Now()}
+}
+
+// startPhase records the start of phase for file, and returns a function that has to be
+// called with the result when phase completes
+func (r *walkReport) startPhase(file *loader.ParsedFile, name string) func(error) {
+	started := time.Now()
+
+	return func(err error) {
+		if r == nil {
+			return
+		}
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		if result, ok := r.results[file]; ok {
+			result.Phases = append(result.Phases, &phaseResult{
+				Name:     name,
+				Duration: time.Since(started),
+				Err:      err,
+			})
+		}
+	}
+}
+
+// setStatus sets status of file, overwriting any previous status
+func (r *walkReport) setStatus(file *loader.ParsedFile, status walkStatus, err error) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result, ok := r.results[file]
+	if !ok {
+		result = &walkResult{Started: time.Now()}
+		r.results[file] = result
+	}
+
+	result.Status = status
+	result.Err = err
+}
+
+// skip marks that file was skipped by command, with reason for skipping it
+func (r *walkReport) skip(file *loader.ParsedFile, reason string) {
+	r.setStatus(file, walkSkipped, nil)
+
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.results[file].Reason = reason
+}
+
+// finish marks that processing of file has completed. It is marked as succeeded unless it
+// already has a status. A file where dependencies could not be resolved does not fail, but
+// should still be reported.
+func (r *walkReport) finish(file *loader.ParsedFile) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if result, ok := r.results[file]; ok {
+		result.Duration = time.Since(result.Started)
+
+		if result.Status == "" {
+			result.Status = walkSucceeded
+		}
+	}
+}
+
+// hasFailed returns true if any file has failed
+func (r *walkReport) hasFailed() bool {
+	return len(r.filesWithStatus(nil, walkFailed)) > 0
+}
+
+// result returns the result of file. Files that have not been processed are skipped,
+// either because a dependency failed or walk stopped on first error.
+func (r *walkReport) result(file *loader.ParsedFile) *walkResult {
+	if r == nil {
+		return &walkResult{Status: walkSkipped}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if result, ok := r.results[file]; ok && result.Status != "" {
+		return result
+	}
+
+	return &walkResult{Status: walkSkipped}
+}
+
+// filesWithStatus returns all files in files with status. If files is nil it returns
+// all files with status, in no particular order.
+func (r *walkReport) filesWithStatus(files loader.ParsedFileCollection, status walkStatus) loader.ParsedFileCollection {
+	if r == nil {
+		return nil
+	}
+
+	if files == nil {
+		r.lock.Lock()
+		for file := range r.results {
+			files = append(files, file)
+		}
+		r.lock.Unlock()
+	}
+
+	matches := loader.ParsedFileCollection{}
+	for _, file := range files {
+		if r.result(file).Status == status {
+			matches = append(matches, file)
+		}
+	}
+
+	return matches
+}
+
+// isProcessed returns false if file is part of files and it failed or was skipped
+func (r *walkReport) isProcessed(files loader.ParsedFileCollection, file *loader.ParsedFile) bool {
+	for _, f := range files {
+		if f == file {
+			status := r.result(file).Status
+			return status != walkFailed && status != walkSkipped
+		}
+	}
+
+	return true
+}
+
+// print prints the result of all files, grouped by status
+func (r *walkReport) print(files loader.ParsedFileCollection) {
+	ui.Separator("Run report")
+
+	groups := []struct {
+		status walkStatus
+		title  string
+		color  *color.Color
+	}{
+		{walkSucceeded, "Succeeded", color.New(color.FgGreen, color.Bold)},
+		{walkFailed, "Failed", color.New(color.FgRed, color.Bold)},
+		{walkSkipped, "Skipped", color.New(color.FgYellow, color.Bold)},
+		{walkUnresolved, "Unresolved dependencies", color.New(color.FgYellow, color.Bold)},
+	}
+
+	for _, group := range groups {
+		matches := r.filesWithStatus(files, group.status)
+		if len(matches) == 0 {
+			continue
+		}
+
+		ui.Info("%s", group.color.Sprintf("%s (%v):", group.title, len(matches)))
+
+		for _, file := range matches {
+			ui.Info("- %s", r.describe(files, file))
+		}
+
+		ui.NewLine()
+	}
+}
+
+// describe returns file name with the reason for its status
+func (r *walkReport) describe(files loader.ParsedFileCollection, file *loader.ParsedFile) string {
+	result := r.result(file)
+
+	switch result.Status {
+	case walkFailed:
+		return fmt.Sprintf("%s: %s", file.Name, result.Err)
+	case walkSkipped:
+		if result.Reason != "" {
+			return fmt.Sprintf("%s: %s", file.Name, result.Reason)
+		}
+
+		names := []string{}
+		for name := range file.Dependencies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			dep := file.Dependencies[name]
+			if !r.isProcessed(files, dep) {
+				if r.result(dep).Reason != "" {
+					return fmt.Sprintf("%s: dependency %s was skipped", file.Name, dep.Name)
+				}
+
+				return fmt.Sprintf("%s: dependency %s did not complete", file.Name, dep.Name)
+			}
+		}
+
+		return fmt.Sprintf("%s: not processed after previous error", file.Name)
+	case walkUnresolved:
+		return fmt.Sprintf("%s: some of the dependencies failed to resolve", file.Name)
+	}
+
+	return file.Name
+}
+
+// jsonReport is the content of report written with --report-json
+type jsonReport struct {
+	Command     string            `json:"command"`
+	Deployments []*jsonDeployment `json:"deployments"`
+}
+
+// jsonDeployment is the result of a single file in json report
+type jsonDeployment struct {
+	Name     string       `json:"name"`
+	Path     string       `json:"path"`
+	Status   walkStatus   `json:"status"`
+	Error    string       `json:"error,omitempty"`
+	Reason   string       `json:"reason,omitempty"`
+	Duration float64      `json:"duration"`
+	Phases   []*jsonPhase `json:"phases"`
+}
+
+// jsonPhase is the result of a single phase in json report
+type jsonPhase struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"duration"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// writeJSON writes the report as json to filename. Durations are in seconds
+func (r *walkReport) writeJSON(filename, command string, files loader.ParsedFileCollection) error {
+	report := &jsonReport{
+		Command:     command,
+		Deployments: []*jsonDeployment{},
+	}
+
+	for _, file := range files {
+		result := r.result(file)
+
+		deployment := &jsonDeployment{
+			Name:     file.Name,
+			Path:     relativeToWorkingDir(file.FullPath),
+			Status:   result.Status,
+			Error:    errorString(result.Err),
+			Reason:   result.Reason,
+			Duration: result.Duration.Seconds(),
+			Phases:   []*jsonPhase{},
+		}
+
+		for _, phase := range result.Phases {
+			deployment.Phases = append(deployment.Phases, &jsonPhase{
+				Name:     phase.Name,
+				Duration: phase.Duration.Seconds(),
+				Error:    errorString(phase.Err),
+			})
+		}
+
+		report.Deployments = append(report.Deployments, deployment)
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, content, os.ModePerm)
+}
+
+// junitTestSuite is the root element of report written with --report-junit
+type junitTestSuite struct {
+	XMLName   xml.Name         `xml:"testsuite"`
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of a single file in junit report
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitMessage is a failure or skipped element in junit report
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes the report as JUnit XML to filename. Each file is a test case, files
+// that were skipped or where dependencies could not be resolved are reported as skipped.
+// Time used in each phase is written to system-out.
+func (r *walkReport) writeJUnit(filename, command string, files loader.ParsedFileCollection) error {
+	suite := &junitTestSuite{
+		Name:      fmt.Sprintf("tau %s", command),
+		Tests:     len(files),
+		TestCases: []*junitTestCase{},
+	}
+
+	var total time.Duration
+
+	for _, file := range files {
+		result := r.result(file)
+		total += result.Duration
+
+		testCase := &junitTestCase{
+			Name:      file.Name,
+			ClassName: relativeToWorkingDir(file.FullPath),
+			Time:      junitTime(result.Duration),
+		}
+
+		switch result.Status {
+		case walkFailed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: errorString(result.Err)}
+		case walkSkipped, walkUnresolved:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: r.describe(files, file)}
+		}
+
+		phases := []string{}
+		for _, phase := range result.Phases {
+			line := fmt.Sprintf("%s: %ss", phase.Name, junitTime(phase.Duration))
+			if phase.Err != nil {
+				line = fmt.Sprintf("%s (%s)", line, phase.Err)
+			}
+
+			phases = append(phases, line)
+		}
+		testCase.SystemOut = strings.Join(phases, "\n")
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	suite.Time = junitTime(total)
+
+	content, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append([]byte(xml.Header), content...), os.ModePerm)
+}
+
+// junitTime returns duration in seconds as used in junit reports
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// errorString returns the error message of err, or empty string if err is nil
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
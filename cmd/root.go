@@ -49,6 +49,15 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newDestroyCmd())
 	rootCmd.AddCommand(newOutputCmd())
 	rootCmd.AddCommand(newFmtCmd())
+	rootCmd.AddCommand(newGraphCmd())
+	rootCmd.AddCommand(newValidateCmd())
+	rootCmd.AddCommand(newCleanCmd())
+	rootCmd.AddCommand(newConsoleCmd())
+	rootCmd.AddCommand(newRenderCmd())
+	rootCmd.AddCommand(newDriftCmd())
+	rootCmd.AddCommand(newUnlockCmd())
+	rootCmd.AddCommand(newNewCmd())
+	rootCmd.AddCommand(newDocsCmd())
 	rootCmd.AddCommand(newVersionCmd())
 
 	for name, cmd := range passThroughCommands {
@@ -58,6 +67,17 @@ func NewRootCmd() *cobra.Command {
 	return rootCmd
 }
 
+// ExitCodeError is returned by commands that should exit with a specific exit code
+// without it being reported as an error, for instance to tell that plan has changes
+type ExitCodeError struct {
+	Code int
+}
+
+// Error implements the error interface
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("exit code %v", e.Code)
+}
+
 // getExtraArgs returns extra terraform arguments, but filters out invalid arguments
 func getExtraArgs(invalidArgs ...string) []string {
 	extraArgs := []string{}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config/loader"
+	"github.com/avinor/tau/pkg/helpers/ui"
+	"github.com/avinor/tau/pkg/lock"
+)
+
+type unlockCmd struct {
+	meta
+
+	force bool
+}
+
+var (
+	// unlockLong is long description of unlock command
+	unlockLong = templates.LongDesc(`Remove tau run locks on selected files. Tau takes a lock
+		on each file while running commands on it, so two runs cannot change the same files
+		in .tau at the same time. The lock records user, host, process id and command of
+		the run holding it.
+
+		If a run did not exit cleanly the lock is left behind and has to be removed with
+		unlock. It asks before removing each lock, unless --force is set. Make sure the
+		run holding the lock is no longer running before removing it.
+
+		This does not remove locks on remote state, use force-unlock for that.
+		`)
+
+	// unlockExample is examples for unlock command
+	unlockExample = templates.Examples(`
+		# Remove lock on module.hcl
+		tau unlock -f module.hcl
+
+		# Remove all locks in current folder without asking
+		tau unlock --force
+	`)
+)
+
+// newUnlockCmd creates a new unlock command
+func newUnlockCmd() *cobra.Command {
+	uc := &unlockCmd{
+		meta: meta{
+			noTerraform: true,
+		},
+	}
+
+	unlockCmd := &cobra.Command{
+		Use:                   "unlock [-f SOURCE]",
+		Short:                 "Remove stale tau run locks",
+		Long:                  unlockLong,
+		Example:               unlockExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := uc.meta.init(args); err != nil {
+				return err
+			}
+
+			return uc.run(args)
+		},
+	}
+
+	f := unlockCmd.Flags()
+	f.BoolVar(&uc.force, "force", false, "remove locks without asking")
+
+	uc.addMetaFlags(unlockCmd)
+	uc.addSelectionFlags(unlockCmd)
+
+	return unlockCmd
+}
+
+func (uc *unlockCmd) run(args []string) error {
+	// load all sources
+	files, err := uc.load()
+	if err != nil {
+		return err
+	}
+
+	ui.Header("Removing locks...")
+
+	for _, file := range files {
+		if err := uc.unlockFile(file); err != nil {
+			return err
+		}
+	}
+
+	ui.NewLine()
+
+	return nil
+}
+
+// unlockFile removes the lock on file, if it is locked
+func (uc *unlockCmd) unlockFile(file *loader.ParsedFile) error {
+	info, err := lock.Read(file.LockFile())
+	if os.IsNotExist(err) {
+		ui.Info("- %s is not locked", file.Name)
+		return nil
+	}
+
+	if err != nil {
+		ui.Warn("- %s has an unreadable lock: %s", file.Name, err)
+	} else {
+		ui.Info("- %s is locked by %s@%s (pid %v) running '%s' since %s", file.Name,
+			info.User, info.Host, info.PID, info.Command, info.Created.Local().Format(time.RFC3339))
+	}
+
+	if !uc.force {
+		answer, err := ui.Ask("  Remove lock? [y/N]:")
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		default:
+			ui.Info("  Lock not removed")
+			return nil
+		}
+	}
+
+	if err := lock.Remove(file.LockFile()); err != nil {
+		return err
+	}
+
+	ui.Info("  Lock removed")
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/avinor/tau/internal/templates"
+	"github.com/avinor/tau/pkg/config"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+type validateCmd struct {
+	meta
+}
+
+var (
+	// validateLong is long description of validate command
+	validateLong = templates.LongDesc(`Validate the configuration files without running terraform.
+		It checks all blocks in every file, including merged auto import files, and that all
+		dependencies and data sources referenced in inputs have been declared. All problems
+		found are reported, it does not stop on first error.
+
+		Command exits with a non-zero exit code if any errors are found, so it can be
+		used as a fast pre-commit check.
+		`)
+
+	// validateExample is examples for validate command
+	validateExample = templates.Examples(`
+		# Validate all files in current folder
+		tau validate
+
+		# Validate a single file
+		tau validate -f module.hcl
+	`)
+)
+
+// newValidateCmd creates a new validate command
+func newValidateCmd() *cobra.Command {
+	vc := &validateCmd{
+		meta: meta{
+			noTerraform: true,
+		},
+	}
+
+	validateCmd := &cobra.Command{
+		Use:                   "validate [-f SOURCE]",
+		Short:                 "Validate configuration files",
+		Long:                  validateLong,
+		Example:               validateExample,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		Args:                  cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := vc.meta.init(args); err != nil {
+				return err
+			}
+
+			return vc.run(args)
+		},
+	}
+
+	vc.addMetaFlags(validateCmd)
+
+	return validateCmd
+}
+
+func (vc *validateCmd) run(args []string) error {
+	ui.Header("Validating files...")
+
+	count, diags := vc.Loader.Validate(vc.files)
+
+	if count == 0 && !diags.HasErrors() {
+		return noSourceInPath
+	}
+
+	if len(diags) > 0 {
+		printDiagnostics(diags)
+	}
+
+	errorCount := 0
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return errors.Errorf("found %v error(s) in %v file(s)", errorCount, count)
+	}
+
+	ui.NewLine()
+	ui.Info(color.New(color.FgGreen, color.Bold).Sprintf("Success! %v file(s) are valid.", count))
+	ui.NewLine()
+
+	return nil
+}
+
+// printDiagnostics prints all diagnostics, including source snippets where possible
+func printDiagnostics(diags hcl.Diagnostics) {
+	var buffer bytes.Buffer
+
+	writer := hcl.NewDiagnosticTextWriter(&buffer, config.Sources(), 0, !color.NoColor)
+	if err := writer.WriteDiagnostics(diags); err != nil {
+		ui.Error("%s", diags.Error())
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n") {
+		ui.Error("%s", line)
+	}
+}
@@ -15,6 +15,10 @@ func main() {
 	log.SetOutput(&ui.Writer{})
 
 	if err := cmd.NewRootCmd().Execute(); err != nil {
+		if exitErr, ok := err.(*cmd.ExitCodeError); ok {
+			os.Exit(exitErr.Code)
+		}
+
 		ui.NewLine()
 		ui.Fatal("Error: %s", err)
 		os.Exit(1)
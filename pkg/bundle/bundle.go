@@ -0,0 +1,386 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManifestName is the name of manifest file in bundle
+	ManifestName = "manifest.json"
+)
+
+var (
+	// manifestMissing is returned when extracting a bundle without a manifest
+	manifestMissing = errors.Errorf("bundle does not contain a manifest")
+
+	// invalidBundlePath is returned when a file in bundle would be written outside destination
+	invalidBundlePath = errors.Errorf("bundle contains a file with invalid path")
+)
+
+// Manifest describes content of a bundle
+type Manifest struct {
+	Version     string        `json:"version"`
+	Created     time.Time     `json:"created"`
+	Deployments []*Deployment `json:"deployments"`
+}
+
+// Deployment is a single deployment in bundle. Dir is the directory of deployment relative
+// to root of bundle, and Files is the sha256 hash of each file, keyed by path relative to
+// root of bundle
+type Deployment struct {
+	Name  string            `json:"name"`
+	Dir   string            `json:"dir"`
+	Files map[string]string `json:"files"`
+}
+
+// MismatchError is returned when a file in bundle does not match the manifest
+type MismatchError struct {
+	Path   string
+	Reason string
+}
+
+// Error implements the error interface
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s in bundle %s", e.Path, e.Reason)
+}
+
+// Create writes a bundle to filename with all files in the directories of deployments.
+// Directories are relative to root. Symbolic links are followed, so the bundle contains the
+// actual files even if they link to a cache outside of root. The hashes of files are added
+// to deployments and the manifest is returned.
+func Create(filename, root, version string, deployments []*Deployment) (*Manifest, error) {
+	out, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+
+	manifest := &Manifest{
+		Version:     version,
+		Created:     time.Now().UTC(),
+		Deployments: deployments,
+	}
+
+	for _, deployment := range deployments {
+		deployment.Dir = filepath.ToSlash(deployment.Dir)
+		deployment.Files = map[string]string{}
+
+		if err := addDir(tw, root, deployment.Dir, deployment.Files); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeEntry(tw, ManifestName, 0644, bytes.NewReader(content), int64(len(content))); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, out.Close()
+}
+
+// Extract extracts bundle filename into dest. Files are first extracted to a temporary
+// directory in dest and verified against manifest, then the directory of each deployment
+// replaces any existing directory in dest. Returns the manifest of bundle.
+func Extract(filename, dest string) (*Manifest, error) {
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	tempDir, err := ioutil.TempDir(dest, "bundle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	hashes, err := extractFiles(filename, tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(tempDir, ManifestName))
+	if os.IsNotExist(err) {
+		return nil, manifestMissing
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := Verify(manifest, hashes); err != nil {
+		return nil, err
+	}
+
+	for _, deployment := range manifest.Deployments {
+		target := filepath.Join(dest, filepath.FromSlash(deployment.Dir))
+
+		if err := os.RemoveAll(target); err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return nil, err
+		}
+
+		if err := os.Rename(filepath.Join(tempDir, filepath.FromSlash(deployment.Dir)), target); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// Verify checks that hashes of extracted files are exactly the files listed in manifest.
+// Manifest itself is not part of hashes
+func Verify(manifest *Manifest, hashes map[string]string) error {
+	expected := map[string]string{}
+
+	for _, deployment := range manifest.Deployments {
+		if !isValidPath(deployment.Dir) {
+			return invalidBundlePath
+		}
+
+		for path, hash := range deployment.Files {
+			if !strings.HasPrefix(path, deployment.Dir+"/") {
+				return &MismatchError{Path: path, Reason: fmt.Sprintf("is not in directory %s", deployment.Dir)}
+			}
+
+			expected[path] = hash
+		}
+	}
+
+	for _, path := range sortedKeys(expected) {
+		hash, ok := hashes[path]
+		if !ok {
+			return &MismatchError{Path: path, Reason: "is missing"}
+		}
+
+		if hash != expected[path] {
+			return &MismatchError{Path: path, Reason: "does not match hash in manifest"}
+		}
+	}
+
+	for _, path := range sortedKeys(hashes) {
+		if _, ok := expected[path]; !ok && path != ManifestName {
+			return &MismatchError{Path: path, Reason: "is not listed in manifest"}
+		}
+	}
+
+	return nil
+}
+
+// addDir adds all files in dir, relative to root, to tw and records their hashes
+func addDir(tw *tar.Writer, root, dir string, hashes map[string]string) error {
+	fullPath := filepath.Join(root, filepath.FromSlash(dir))
+
+	entries, err := ioutil.ReadDir(fullPath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		entryPath := filepath.Join(fullPath, entry.Name())
+
+		// Stat follows symbolic links
+		fi, err := os.Stat(entryPath)
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if err := addDir(tw, root, name, hashes); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+
+		hash, err := hashFile(entryPath)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(entryPath)
+		if err != nil {
+			return err
+		}
+
+		err = writeEntry(tw, name, fi.Mode(), f, fi.Size())
+		f.Close()
+
+		if err != nil {
+			return err
+		}
+
+		hashes[name] = hash
+	}
+
+	return nil
+}
+
+// writeEntry writes a single file to tw
+func writeEntry(tw *tar.Writer, name string, mode os.FileMode, r io.Reader, size int64) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// extractFiles extracts all files in bundle filename to dest, and returns the sha256 hash
+// of each file extracted
+func extractFiles(filename, dest string) (map[string]string, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	gr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	hashes := map[string]string{}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if !isValidPath(header.Name) {
+			return nil, invalidBundlePath
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(header.Name))
+
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return nil, err
+		}
+
+		hash, err := writeFile(target, os.FileMode(header.Mode).Perm(), tr)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[header.Name] = hash
+	}
+
+	return hashes, nil
+}
+
+// writeFile writes content of r to filename and returns the sha256 hash of content
+func writeFile(filename string, mode os.FileMode, r io.Reader) (string, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), f.Close()
+}
+
+// hashFile returns the sha256 hash of file content
+func hashFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isValidPath returns true if path is a relative path that does not point outside of
+// the directory it is relative to
+func isValidPath(p string) bool {
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(p) {
+		return false
+	}
+
+	cleaned := path.Clean(p)
+
+	return cleaned != "." && cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
+
+// sortedKeys returns keys of m sorted, so errors are reported in same order every time
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
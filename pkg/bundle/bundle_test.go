@@ -0,0 +1,150 @@
+package bundle
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAndExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	dest := filepath.Join(dir, "dest")
+
+	writeTestFile(t, filepath.Join(root, "a.hcl", "module", "main.tf"), "a")
+	writeTestFile(t, filepath.Join(root, "envs", "b.hcl", "module", "tau.tfplan"), "plan")
+	writeTestFile(t, filepath.Join(dir, "cache", "provider"), "provider")
+	writeTestFile(t, filepath.Join(dest, "a.hcl", "module", "stale.tf"), "stale")
+	writeTestFile(t, filepath.Join(dest, "a.hcl", "dep", "logs", "main.tf"), "dependency")
+
+	if err := os.Symlink(filepath.Join(dir, "cache"), filepath.Join(root, "a.hcl", "module", "plugins")); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, "bundle.tar.gz")
+
+	manifest, err := Create(filename, root, "test", []*Deployment{
+		{Name: "a.hcl", Dir: "a.hcl/module"},
+		{Name: "envs/b.hcl", Dir: "envs/b.hcl/module"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, manifest.Deployments[0].Files, 2)
+	assert.Contains(t, manifest.Deployments[0].Files, "a.hcl/module/plugins/provider")
+	assert.Len(t, manifest.Deployments[1].Files, 1)
+
+	extracted, err := Extract(filename, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, manifest.Deployments[0].Files, extracted.Deployments[0].Files)
+	assert.Equal(t, "test", extracted.Version)
+
+	tests := []struct {
+		Path    string
+		Content string
+		Exists  bool
+	}{
+		{"a.hcl/module/main.tf", "a", true},
+		{"a.hcl/module/plugins/provider", "provider", true},
+		{"a.hcl/module/stale.tf", "", false},
+		{"a.hcl/dep/logs/main.tf", "dependency", true},
+		{"envs/b.hcl/module/tau.tfplan", "plan", true},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			content, err := ioutil.ReadFile(filepath.Join(dest, filepath.FromSlash(test.Path)))
+
+			if !test.Exists {
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Content, string(content))
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	manifest := &Manifest{
+		Deployments: []*Deployment{
+			{Name: "a.hcl", Dir: "a.hcl/module", Files: map[string]string{
+				"a.hcl/module/main.tf":    "1",
+				"a.hcl/module/tau.tfplan": "2",
+			}},
+		},
+	}
+
+	tests := []struct {
+		Manifest *Manifest
+		Hashes   map[string]string
+		Error    string
+	}{
+		{manifest, map[string]string{"a.hcl/module/main.tf": "1", "a.hcl/module/tau.tfplan": "2"}, ""},
+		{manifest, map[string]string{"a.hcl/module/main.tf": "1", "a.hcl/module/tau.tfplan": "2", ManifestName: "3"}, ""},
+		{manifest, map[string]string{"a.hcl/module/main.tf": "1"}, "a.hcl/module/tau.tfplan in bundle is missing"},
+		{manifest, map[string]string{"a.hcl/module/main.tf": "1", "a.hcl/module/tau.tfplan": "3"}, "a.hcl/module/tau.tfplan in bundle does not match hash in manifest"},
+		{manifest, map[string]string{"a.hcl/module/main.tf": "1", "a.hcl/module/tau.tfplan": "2", "a.hcl/module/x.tf": "4"}, "a.hcl/module/x.tf in bundle is not listed in manifest"},
+		{&Manifest{Deployments: []*Deployment{{Dir: "../a.hcl"}}}, map[string]string{}, "bundle contains a file with invalid path"},
+		{&Manifest{Deployments: []*Deployment{{Dir: "a.hcl", Files: map[string]string{"b.hcl/main.tf": "1"}}}}, map[string]string{}, "b.hcl/main.tf in bundle is not in directory a.hcl"},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			err := Verify(test.Manifest, test.Hashes)
+
+			if test.Error == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, test.Error)
+		})
+	}
+}
+
+func TestIsValidPath(t *testing.T) {
+	tests := []struct {
+		Path     string
+		Expected bool
+	}{
+		{"a.hcl/module/main.tf", true},
+		{"envs/dev/a.hcl/module", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../a.hcl", false},
+		{"a.hcl/../../b.hcl", false},
+		{"/tmp/a.hcl", false},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, isValidPath(test.Path))
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, filename, content string) {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,7 @@
+// Package bundle packages directories of deployments into a single archive, so they can be
+// moved between machines. The archive is a gzipped tar file with a manifest that lists the
+// sha256 hash of every file in the bundle.
+//
+// When extracting a bundle all files are verified against the manifest before any
+// existing directories are replaced.
+package bundle
@@ -21,6 +21,13 @@ type Config struct {
 	Backend      *Backend      `hcl:"backend,block"`
 	Module       *Module       `hcl:"module,block"`
 	Inputs       *Inputs       `hcl:"inputs,block"`
+	Locals       []*Locals     `hcl:"locals,block"`
+	Variables    []*Variable   `hcl:"variable,block"`
+	Includes     []*Include    `hcl:"include,block"`
+
+	// Workspace is the terraform workspace to deploy module to. Tau selects, or creates,
+	// the workspace after init. Current workspace is not changed if not set
+	Workspace string `hcl:"workspace,optional"`
 }
 
 // Merge all sources into current configuration struct.
@@ -54,9 +61,32 @@ func (c *Config) Merge(srcs []*Config) error {
 		return err
 	}
 
+	if err := mergeLocals(c, srcs); err != nil {
+		return err
+	}
+
+	if err := mergeVariables(c, srcs); err != nil {
+		return err
+	}
+
+	if err := mergeIncludes(c, srcs); err != nil {
+		return err
+	}
+
+	mergeWorkspace(c, srcs)
+
 	return nil
 }
 
+// mergeWorkspace sets workspace from last source that defines it
+func mergeWorkspace(dest *Config, srcs []*Config) {
+	for _, src := range srcs {
+		if src.Workspace != "" {
+			dest.Workspace = src.Workspace
+		}
+	}
+}
+
 // PostProcess is called after merging all configurations together to perform additional
 // processing after config is read. Can modify config elements
 func (c *Config) PostProcess(file *File) {
@@ -1 +1,63 @@
 package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	workspaceTest1 = `
+		workspace = "dev"
+	`
+
+	workspaceTest2 = `
+		workspace = "prod"
+	`
+
+	workspaceTest3 = `
+		module {
+			source = "./"
+		}
+	`
+)
+
+var (
+	workspaceFile1, _ = NewFile("/workspace1", []byte(workspaceTest1))
+	workspaceFile2, _ = NewFile("/workspace2", []byte(workspaceTest2))
+	workspaceFile3, _ = NewFile("/workspace3", []byte(workspaceTest3))
+)
+
+func TestWorkspaceMerge(t *testing.T) {
+	tests := []struct {
+		Files    []*File
+		Expected string
+	}{
+		{
+			[]*File{workspaceFile1},
+			"dev",
+		},
+		{
+			[]*File{workspaceFile1, workspaceFile2},
+			"prod",
+		},
+		{
+			[]*File{workspaceFile2, workspaceFile3},
+			"prod",
+		},
+		{
+			[]*File{workspaceFile3},
+			"",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			config := &Config{}
+			mergeWorkspace(config, getConfigFromFiles(t, test.Files))
+
+			assert.Equal(t, test.Expected, config.Workspace)
+		})
+	}
+}
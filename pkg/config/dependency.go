@@ -20,10 +20,14 @@ var (
 // If RunInSeparateEnv is set to true it should fork a new environment that resolves all
 // dependencies in separate process (environment relative to dependency). Otherwise it will
 // resolve all dependencies in same environment as current execution.
+//
+// Workspace is the terraform workspace to read outputs from. If not set it reads from the
+// workspace configured in dependency file.
 type Dependency struct {
 	Name             string `hcl:"name,label"`
 	Source           string `hcl:"source,attr"`
 	RunInSeparateEnv bool   `hcl:"run_in_separate_env,optional"`
+	Workspace        string `hcl:"workspace,optional"`
 
 	Backend *Backend `hcl:"backend,block"`
 }
@@ -47,6 +51,10 @@ func (d *Dependency) Merge(src *Dependency) error {
 		d.RunInSeparateEnv = src.RunInSeparateEnv
 	}
 
+	if src.Workspace != "" {
+		d.Workspace = src.Workspace
+	}
+
 	if d.Backend == nil && src.Backend != nil {
 		d.Backend = src.Backend
 		return nil
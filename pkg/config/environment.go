@@ -52,16 +52,8 @@ func (e Environment) Validate() (bool, error) {
 	}
 
 	for _, attr := range attrs {
-		if !envRegexp.MatchString(attr.Name) {
-			return false, envVariableNotMatch
-		}
-
-		if _, diags := hcl.ExprMap(attr.Expr); diags == nil {
-			return false, envCannotContainMap
-		}
-
-		if _, diags := hcl.ExprList(attr.Expr); diags == nil {
-			return false, envCannotContainList
+		if err := validateEnvAttribute(attr); err != nil {
+			return false, err
 		}
 	}
 
@@ -90,6 +82,24 @@ func (e *Environment) Parse(context *hcl.EvalContext) (map[string]string, error)
 	return env, nil
 }
 
+// validateEnvAttribute checks that attribute has a valid environment variable name and
+// is not a list or map
+func validateEnvAttribute(attr *hcl.Attribute) error {
+	if !envRegexp.MatchString(attr.Name) {
+		return envVariableNotMatch
+	}
+
+	if _, diags := hcl.ExprMap(attr.Expr); diags == nil {
+		return envCannotContainMap
+	}
+
+	if _, diags := hcl.ExprList(attr.Expr); diags == nil {
+		return envCannotContainList
+	}
+
+	return nil
+}
+
 // mergeEnvironments merges only the environment from all configurations in srcs into dest
 func mergeEnvironments(dest *Config, srcs []*Config) error {
 	for _, src := range srcs {
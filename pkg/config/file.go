@@ -32,6 +32,15 @@ type File struct {
 
 	// context to evaluate expressions with. New variables can be added to this by calling AddToContext()
 	context *hcl.EvalContext
+
+	// variableValues are values of variables declared in file, set with SetVariableValues()
+	variableValues map[string]cty.Value
+}
+
+// Sources returns all files that have been parsed so far, keyed by filename. It can be used
+// to include source code snippets when printing diagnostics.
+func Sources() map[string]*hcl.File {
+	return parser.Files()
 }
 
 // NewFile returns a new File. It will check that it exists and read content, but not parse it
@@ -56,6 +65,11 @@ func (f *File) AddChild(file *File) {
 	f.children = append(f.children, file)
 }
 
+// Children returns all files added as children of this file, in the order they were added
+func (f *File) Children() []*File {
+	return f.children
+}
+
 // AddToContext adds a variable to the evaluation context of this file
 func (f *File) AddToContext(key string, value cty.Value) {
 	f.context.Variables[key] = value
@@ -69,6 +83,10 @@ func (f *File) EvalContext() *hcl.EvalContext {
 // Config returns the full configuration for file. This includes the merged configuration from
 // all children. Should only call this once as it will do full parsing of file and all children
 func (f *File) Config() (*Config, error) {
+	if diags := f.evaluateContext(); diags.HasErrors() {
+		return nil, diags
+	}
+
 	configs := []*Config{}
 
 	for _, file := range append(f.children, f) {
@@ -90,6 +108,17 @@ func (f *File) Config() (*Config, error) {
 	return config, nil
 }
 
+// MergeChain returns names of all files merged into configuration, in the order they are
+// merged. Later files take precedence, so file itself is always last
+func (f *File) MergeChain() []string {
+	names := []string{}
+	for _, child := range f.children {
+		names = append(names, child.Name)
+	}
+
+	return append(names, f.Name)
+}
+
 func (f *File) String() string {
 	if len(f.children) == 0 {
 		return f.Name
@@ -103,22 +132,44 @@ func (f *File) String() string {
 	return fmt.Sprintf("%s (included %s)", f.Name, strings.Join(children, ", "))
 }
 
+// evaluateContext adds variables and locals declared in file and children to the evaluation
+// context. Variables are evaluated first, so locals can reference them
+func (f *File) evaluateContext() hcl.Diagnostics {
+	diags := f.evaluateVariables()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	return append(diags, f.evaluateLocals()...)
+}
+
 // parse the file using evaluation context from input. It will add source variables to the context
 // variables if not set that makes it possible to retrieve file name etc
 func (f *File) parse(context *hcl.EvalContext) (*Config, error) {
+	config, diags := f.decode(context)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return config, nil
+}
+
+// decode parses and decodes the file the same way as parse, but returns all diagnostics
+// instead of an error. Config is nil if file could not be decoded.
+func (f *File) decode(context *hcl.EvalContext) (*Config, hcl.Diagnostics) {
 	hclFile, diags := parser.ParseHCL(f.Content, f.FullPath)
 	if diags.HasErrors() {
 		return nil, diags
 	}
 
 	config := &Config{}
-	bodyDiags := gohcl.DecodeBody(hclFile.Body, context, config)
+	diags = append(diags, gohcl.DecodeBody(hclFile.Body, context, config)...)
 
-	if bodyDiags.HasErrors() {
-		return nil, bodyDiags
+	if diags.HasErrors() {
+		return nil, diags
 	}
 
-	return config, nil
+	return config, diags
 }
 
 // GetEvalContext gets the context for this file. Adding variables for source to default context
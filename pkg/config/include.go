@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+
+	"github.com/avinor/tau/pkg/config/comp"
+	hclcontext "github.com/avinor/tau/pkg/helpers/hcl"
+)
+
+var (
+	// includesSchema is the schema to read only include blocks from a file
+	includesSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "include", LabelNames: []string{"name"}},
+		},
+	}
+
+	// includeBlockSchema is the schema of an include block
+	includeBlockSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "path", Required: true},
+		},
+	}
+)
+
+// Include merges another file into this one. Path is relative to the file the include block
+// is in, and can use find_in_parent_folders() to search for a file in parent folders. Files
+// are included when loading the file, so they are only kept for reference after that.
+type Include struct {
+	Name   string   `hcl:"name,label"`
+	Config hcl.Body `hcl:",remain"`
+
+	comp.Remainer
+}
+
+// mergeIncludes merges the include arrays into destination config
+func mergeIncludes(dest *Config, srcs []*Config) error {
+	for _, src := range srcs {
+		dest.Includes = append(dest.Includes, src.Includes...)
+	}
+
+	return nil
+}
+
+// IncludePaths returns the absolute path of each file included in file, in the order the
+// include blocks are defined. Paths are evaluated with context, but find_in_parent_folders
+// and relative paths are always relative to directory of file. Includes in children are
+// not returned.
+func (f *File) IncludePaths(context *hcl.EvalContext) ([]string, hcl.Diagnostics) {
+	hclFile, diags := parser.ParseHCL(f.Content, f.FullPath)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	content, _, diags := hclFile.Body.PartialContent(includesSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	dir := filepath.Dir(f.FullPath)
+
+	ctx := context.NewChild()
+	ctx.Functions = map[string]function.Function{
+		"find_in_parent_folders": hclcontext.FindInParentFoldersFunc(dir),
+	}
+
+	paths := []string{}
+	names := map[string]*hcl.Block{}
+
+	for _, block := range content.Blocks {
+		name := block.Labels[0]
+
+		if prev, exists := names[name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate include block",
+				Detail:   fmt.Sprintf("An include named %q was already defined at %s.", name, prev.DefRange),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		names[name] = block
+
+		blockContent, blockDiags := block.Body.Content(includeBlockSchema)
+		diags = append(diags, blockDiags...)
+
+		if blockDiags.HasErrors() {
+			continue
+		}
+
+		attr := blockContent.Attributes["path"]
+		value, valueDiags := attr.Expr.Value(ctx)
+		diags = append(diags, valueDiags...)
+
+		if valueDiags.HasErrors() {
+			continue
+		}
+
+		if value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid include path",
+				Detail:   fmt.Sprintf("Path of include %q must be a string.", name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			continue
+		}
+
+		path := value.AsString()
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		paths = append(paths, filepath.Clean(path))
+	}
+
+	return paths, diags
+}
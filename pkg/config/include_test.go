@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	includeTest1 = `
+		include "common" {
+			path = "../common.hcl"
+		}
+
+		include "region" {
+			path = "${source.name}-region.hcl"
+		}
+	`
+
+	includeTest2 = `
+		include "org" {
+			path = find_in_parent_folders("org.hcl")
+		}
+	`
+
+	includeTest3 = `
+		include "org" {
+			path = find_in_parent_folders("missing.hcl")
+		}
+	`
+
+	includeTest4 = `
+		include "common" {
+			path = "common.hcl"
+		}
+
+		include "common" {
+			path = "other.hcl"
+		}
+	`
+
+	includeTest5 = `
+		include "common" {
+		}
+	`
+)
+
+func TestIncludePaths(t *testing.T) {
+	root, err := ioutil.TempDir("", "tau-include")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "prod", "westeurope")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, "org.hcl"), []byte(""), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Content  string
+		Expected []string
+		Error    string
+	}{
+		{
+			includeTest1,
+			[]string{filepath.Join(root, "prod", "common.hcl"), filepath.Join(dir, "include00-region.hcl")},
+			"",
+		},
+		{
+			includeTest2,
+			[]string{filepath.Join(root, "org.hcl")},
+			"",
+		},
+		{
+			includeTest3,
+			nil,
+			"could not find missing.hcl in any parent folder",
+		},
+		{
+			includeTest4,
+			nil,
+			"Duplicate include block",
+		},
+		{
+			includeTest5,
+			nil,
+			"Missing required argument",
+		},
+		{
+			"module {\n source = \"./\"\n}\n",
+			[]string{},
+			"",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			file, _ := NewFile(filepath.Join(dir, fmt.Sprintf("include%02d.hcl", i)), []byte(test.Content))
+
+			paths, diags := file.IncludePaths(file.EvalContext())
+
+			if test.Error != "" {
+				assert.Contains(t, diags.Error(), test.Error)
+				return
+			}
+
+			assert.False(t, diags.HasErrors(), diags.Error())
+			assert.Equal(t, test.Expected, paths)
+		})
+	}
+}
@@ -26,23 +26,32 @@ var (
 // AddAutoImports searches in the directory for file for any auto imports and add them to the
 // list of children.
 func AddAutoImports(file *config.File) error {
-	dir := filepath.Dir(file.FullPath)
+	autoFiles, err := AutoImports(filepath.Dir(file.FullPath))
+	if err != nil {
+		return err
+	}
 
+	addAutoChildren(file, autoFiles)
+	return nil
+}
+
+// AutoImports returns all auto import files in directory dir. Files are only read once,
+// later calls for same directory return the cached files.
+func AutoImports(dir string) ([]*config.File, error) {
 	if _, exists := autoImportPaths[dir]; exists {
-		addAutoChildren(file, autoImportPaths[dir])
-		return nil
+		return autoImportPaths[dir], nil
 	}
 
 	autoFiles, err := findFiles(dir, autoMatchFunc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	cacheList := []*config.File{}
 	for _, af := range autoFiles {
 		configFile, err := readConfigFile(af)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		cacheList = append(cacheList, configFile)
@@ -50,8 +59,7 @@ func AddAutoImports(file *config.File) error {
 
 	autoImportPaths[dir] = cacheList
 
-	addAutoChildren(file, cacheList)
-	return nil
+	return cacheList, nil
 }
 
 // addAutoChildren calls AddChild for each children on the config file `file`
@@ -0,0 +1,129 @@
+package loader
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/avinor/tau/pkg/helpers/paths"
+)
+
+// changedSince returns absolute path of all files changed in git since ref. It compares
+// with the merge base of ref and HEAD, so changes made on ref after branching off are not
+// included. Uncommitted and untracked files are included. Only local git is used.
+func (l *Loader) changedSince(ref string) ([]string, error) {
+	dir := l.options.WorkingDirectory
+
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := git(dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := git(dir, "diff", "--name-only", "-z", string(base))
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "--full-name", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for _, name := range append(splitGitPaths(diff), splitGitPaths(untracked)...) {
+		changed = append(changed, filepath.Join(string(root), filepath.FromSlash(name)))
+	}
+
+	return changed, nil
+}
+
+// sourcePaths returns all paths that file is created from. That is the file itself, auto
+// imported files, local module source and local hook commands and scripts. Local sources
+// are relative to working directory, same as when retrieving them.
+func (l *Loader) sourcePaths(file *ParsedFile) []string {
+	sources := []string{file.FullPath}
+
+	for _, child := range file.Children() {
+		sources = append(sources, child.FullPath)
+	}
+
+	if module := file.Config.Module; module != nil && module.Version == "" && isLocalSource(module.Source) {
+		sources = append(sources, paths.Abs(l.options.WorkingDirectory, module.Source))
+	}
+
+	for _, hook := range file.Config.Hooks {
+		if hook.Command != nil && isLocalSource(*hook.Command) {
+			sources = append(sources, paths.Abs(l.options.WorkingDirectory, *hook.Command))
+		}
+
+		if hook.Script != nil && isLocalSource(*hook.Script) {
+			sources = append(sources, paths.Abs(l.options.WorkingDirectory, *hook.Script))
+		}
+	}
+
+	return sources
+}
+
+// isChanged returns true if any of sources, or a file in sources if it is a directory,
+// is in changed. Files that are prefixed to be deleted are matched with their original name.
+func isChanged(sources, changed []string) bool {
+	for _, path := range changed {
+		if del, altered := shouldDeleteFile(path); del {
+			changed = append(changed, altered)
+		}
+	}
+
+	for _, source := range sources {
+		source = filepath.Clean(source)
+
+		for _, path := range changed {
+			if path == source || strings.HasPrefix(path, source+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isLocalSource returns true if source is a path on local disk
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, ".") || filepath.IsAbs(source)
+}
+
+// splitGitPaths splits the null separated output from git
+func splitGitPaths(content []byte) []string {
+	names := []string{}
+
+	for _, name := range strings.Split(string(content), "\x00") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// git runs git with args in dir and returns output, without trailing new line
+func git(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return bytes.TrimRight(stdout.Bytes(), "\n"), nil
+}
@@ -0,0 +1,70 @@
+package loader
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsChanged(t *testing.T) {
+	tests := []struct {
+		Sources  []string
+		Changed  []string
+		Expected bool
+	}{
+		{[]string{"/repo/a.hcl"}, []string{"/repo/a.hcl"}, true},
+		{[]string{"/repo/a.hcl"}, []string{"/repo/b.hcl"}, false},
+		{[]string{"/repo/a.hcl", "/repo/common_auto.hcl"}, []string{"/repo/common_auto.hcl"}, true},
+		{[]string{"/repo/a.hcl", "/repo/modules/vnet"}, []string{"/repo/modules/vnet/main.tf"}, true},
+		{[]string{"/repo/a.hcl", "/repo/modules/vnet"}, []string{"/repo/modules/vnet2/main.tf"}, false},
+		{[]string{"/repo/a.hcl", "/repo/modules/vnet/"}, []string{"/repo/modules/vnet/main.tf"}, true},
+		{[]string{"/repo/a.hcl"}, []string{"/repo/DELETE_a.hcl"}, true},
+		{[]string{"/repo/a.hcl"}, []string{"/repo/destroy_a.hcl"}, true},
+		{[]string{"/repo/a.hcl"}, []string{}, false},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, isChanged(test.Sources, test.Changed))
+		})
+	}
+}
+
+func TestSplitGitPaths(t *testing.T) {
+	tests := []struct {
+		Content  string
+		Expected []string
+	}{
+		{"", []string{}},
+		{"a.hcl\x00", []string{"a.hcl"}},
+		{"a.hcl\x00dir/b.hcl\x00", []string{"a.hcl", "dir/b.hcl"}},
+		{"with space.hcl\x00\n", []string{"with space.hcl"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, splitGitPaths([]byte(test.Content)))
+		})
+	}
+}
+
+func TestIsLocalSource(t *testing.T) {
+	tests := []struct {
+		Source   string
+		Expected bool
+	}{
+		{"./module", true},
+		{"../modules/vnet", true},
+		{"/tmp/module", true},
+		{"avinor/kubernetes/azurerm", false},
+		{"git::https://github.com/avinor/tau.git", false},
+		{"az", false},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, isLocalSource(test.Source))
+		})
+	}
+}
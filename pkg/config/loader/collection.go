@@ -1,7 +1,7 @@
 package loader
 
 import (
-	"sync"
+	"sort"
 
 	"github.com/hashicorp/terraform/dag"
 	"github.com/hashicorp/terraform/tfdiags"
@@ -11,8 +11,6 @@ import (
 var (
 	// moduleNotInitError is returned when a module is not initialized
 	moduleNotInitError = errors.Errorf("module is not initialized")
-
-	lock = sync.Mutex{}
 )
 
 // ParsedFileCollection is a collection of parsed files. Using this it is easier to perform
@@ -33,10 +31,38 @@ func (c ParsedFileCollection) IsAllInitialized() error {
 	return nil
 }
 
-// Walk travers the files in collection and execute them in correct
-// order depending on dependencies. It could do it in parallell but has
-// been limited to do one at the time to not mess up output now
+// Walk travers the files in collection and execute them in correct order depending on
+// dependencies. Only one file is processed at the time, use WalkParallel to process
+// independent files concurrently.
 func (c ParsedFileCollection) Walk(walkerFunc WalkFunc) error {
+	return c.WalkParallel(1, walkerFunc)
+}
+
+// WalkParallel travers the files in collection in correct order depending on dependencies,
+// same as Walk, but processes up to parallelism files at the same time. A file is never
+// processed before all its dependencies in collection have completed successfully.
+// Parallelism less than 1 is treated as 1.
+func (c ParsedFileCollection) WalkParallel(parallelism int, walkerFunc WalkFunc) error {
+	return walkGraph(c.Graph(), parallelism, walkerFunc)
+}
+
+// WalkReverse travers the files in collection in reverse dependency order, so a file is
+// always processed before its dependencies. Use this when destroying resources.
+func (c ParsedFileCollection) WalkReverse(walkerFunc WalkFunc) error {
+	return c.WalkReverseParallel(1, walkerFunc)
+}
+
+// WalkReverseParallel travers the files in reverse dependency order, same as WalkReverse,
+// but processes up to parallelism files at the same time. A file is never processed before
+// all files in collection that depend on it have completed successfully.
+func (c ParsedFileCollection) WalkReverseParallel(parallelism int, walkerFunc WalkFunc) error {
+	return walkGraph(c.ReverseGraph(), parallelism, walkerFunc)
+}
+
+// Graph returns the dependency graph of files in collection. Each file has an edge to
+// the dependencies it has that are also part of collection, dependencies outside of
+// collection are ignored. This is the graph used when walking the collection.
+func (c ParsedFileCollection) Graph() *dag.AcyclicGraph {
 	graph := &dag.AcyclicGraph{}
 
 	for _, file := range c {
@@ -51,11 +77,120 @@ func (c ParsedFileCollection) Walk(walkerFunc WalkFunc) error {
 		}
 	}
 
+	return graph
+}
+
+// WithDependencies returns a new collection with all files in collection and all their
+// dependencies, recursively. Dependencies are only found as deep as they have been loaded,
+// see Options.MaxDepth. Files in collection keep their order, dependencies are added last.
+func (c ParsedFileCollection) WithDependencies() ParsedFileCollection {
+	files := append(ParsedFileCollection{}, c...)
+
+	for idx := 0; idx < len(files); idx++ {
+		for _, name := range sortedDependencyNames(files[idx]) {
+			dep := files[idx].Dependencies[name]
+
+			if !contains(files, dep) {
+				files = append(files, dep)
+			}
+		}
+	}
+
+	return files
+}
+
+// WithDependents returns a new collection with all files in collection and all files in
+// candidates that depend on any of them, recursively. Files in collection keep their
+// order, dependents are added last.
+func (c ParsedFileCollection) WithDependents(candidates ParsedFileCollection) ParsedFileCollection {
+	files := append(ParsedFileCollection{}, c...)
+
+	for added := true; added; {
+		added = false
+
+		for _, candidate := range candidates {
+			if contains(files, candidate) {
+				continue
+			}
+
+			for _, dep := range candidate.Dependencies {
+				if contains(files, dep) {
+					files = append(files, candidate)
+					added = true
+					break
+				}
+			}
+		}
+	}
+
+	return files
+}
+
+// Without returns a new collection without the files that have a full path in filenames
+func (c ParsedFileCollection) Without(filenames []string) ParsedFileCollection {
+	excluded := map[string]bool{}
+	for _, filename := range filenames {
+		excluded[filename] = true
+	}
+
+	files := ParsedFileCollection{}
+	for _, file := range c {
+		if !excluded[file.FullPath] {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// sortedDependencyNames returns the names of dependencies for file sorted, so dependencies
+// are always processed in same order
+func sortedDependencyNames(file *ParsedFile) []string {
+	names := []string{}
+	for name := range file.Dependencies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ReverseGraph returns the dependency graph of files in collection with all edges reversed.
+// Each dependency has an edge to the files that depend on it.
+func (c ParsedFileCollection) ReverseGraph() *dag.AcyclicGraph {
+	graph := &dag.AcyclicGraph{}
+
+	for _, file := range c {
+		graph.Add(file)
+	}
+
+	for _, file := range c {
+		for _, dep := range file.Dependencies {
+			if contains(c, dep) {
+				graph.Connect(dag.BasicEdge(dep, file))
+			}
+		}
+	}
+
+	return graph
+}
+
+// walkGraph walks graph and calls walkerFunc for each file, up to parallelism at the
+// same time. Parallelism less than 1 is treated as 1.
+func walkGraph(graph *dag.AcyclicGraph, parallelism int, walkerFunc WalkFunc) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	semaphore := make(chan struct{}, parallelism)
+
 	return graph.Walk(func(vertex dag.Vertex) tfdiags.Diagnostics {
 		var diags tfdiags.Diagnostics
 
-		lock.Lock()
-		defer lock.Unlock()
+		semaphore <- struct{}{}
+		defer func() { <-semaphore }()
+
 		if err := walkerFunc(vertex.(*ParsedFile)); err != nil {
 			return diags.Append(err)
 		}
@@ -2,7 +2,9 @@ package loader
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -110,3 +112,174 @@ func TestCollectionVisit(t *testing.T) {
 		})
 	}
 }
+
+// TestCollectionWalkParallel tests that walking in parallel never runs more than
+// parallelism files at the same time, and that dependencies in collection are
+// completed before the files that depend on them are started.
+func TestCollectionWalkParallel(t *testing.T) {
+	input := ParsedFileCollection{modA, modB, modC, modD, modE, modG, modI, modK}
+
+	for _, parallelism := range []int{0, 1, 2, 4} {
+		t.Run(fmt.Sprintf("%02d", parallelism), func(t *testing.T) {
+			lock := sync.Mutex{}
+			running := 0
+			maxRunning := 0
+			completed := map[*ParsedFile]bool{}
+
+			err := input.WalkParallel(parallelism, func(file *ParsedFile) error {
+				lock.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				for _, dep := range file.Dependencies {
+					assert.True(t, completed[dep], "%s started before %s", file.Name, dep.Name)
+				}
+				lock.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				lock.Lock()
+				running--
+				completed[file] = true
+				lock.Unlock()
+
+				return nil
+			})
+
+			expectedMax := parallelism
+			if expectedMax < 1 {
+				expectedMax = 1
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, completed, len(input))
+			assert.LessOrEqual(t, maxRunning, expectedMax)
+		})
+	}
+}
+
+// TestCollectionWithDependencies tests that dependencies are added recursively and only once
+func TestCollectionWithDependencies(t *testing.T) {
+	tests := []struct {
+		Input   ParsedFileCollection
+		Expects ParsedFileCollection
+	}{
+		{
+			ParsedFileCollection{modA, modB},
+			ParsedFileCollection{modA, modB},
+		},
+		{
+			ParsedFileCollection{modI},
+			ParsedFileCollection{modI, modG, modA},
+		},
+		{
+			ParsedFileCollection{modK, modI},
+			ParsedFileCollection{modK, modI, modA, modG},
+		},
+		{
+			ParsedFileCollection{modGw},
+			ParsedFileCollection{modGw, modLogs, modSpoke, modHub},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expects, test.Input.WithDependencies())
+		})
+	}
+}
+
+// TestCollectionWithDependents tests that all files in candidates depending on files in
+// collection, directly or through other candidates, are added
+func TestCollectionWithDependents(t *testing.T) {
+	candidates := ParsedFileCollection{modA, modB, modC, modD, modE, modG, modI, modK}
+
+	tests := []struct {
+		Input   ParsedFileCollection
+		Expects ParsedFileCollection
+	}{
+		{
+			ParsedFileCollection{modB},
+			ParsedFileCollection{modB},
+		},
+		{
+			ParsedFileCollection{modG},
+			ParsedFileCollection{modG, modI, modK},
+		},
+		{
+			ParsedFileCollection{modA},
+			ParsedFileCollection{modA, modD, modE, modG, modI, modK},
+		},
+		{
+			ParsedFileCollection{modI},
+			ParsedFileCollection{modI},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.ElementsMatch(t, test.Expects, test.Input.WithDependents(candidates))
+		})
+	}
+}
+
+// TestCollectionWithout tests that files are removed by full path
+func TestCollectionWithout(t *testing.T) {
+	fileA := &ParsedFile{File: &config.File{Name: "a.hcl", FullPath: "/tmp/a.hcl"}}
+	fileB := &ParsedFile{File: &config.File{Name: "b.hcl", FullPath: "/tmp/b.hcl"}}
+	fileC := &ParsedFile{File: &config.File{Name: "c.hcl", FullPath: "/tmp/sub/c.hcl"}}
+
+	tests := []struct {
+		Exclude []string
+		Expects ParsedFileCollection
+	}{
+		{[]string{}, ParsedFileCollection{fileA, fileB, fileC}},
+		{[]string{"/tmp/b.hcl"}, ParsedFileCollection{fileA, fileC}},
+		{[]string{"/tmp/a.hcl", "/tmp/sub/c.hcl"}, ParsedFileCollection{fileB}},
+		{[]string{"/tmp/c.hcl"}, ParsedFileCollection{fileA, fileB, fileC}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			files := ParsedFileCollection{fileA, fileB, fileC}
+			assert.Equal(t, test.Expects, files.Without(test.Exclude))
+		})
+	}
+}
+
+// TestCollectionWalkReverseParallel tests that files that depend on another file in
+// collection are completed before the dependency is started
+func TestCollectionWalkReverseParallel(t *testing.T) {
+	input := ParsedFileCollection{modA, modB, modC, modD, modE, modG, modI, modK}
+
+	for _, parallelism := range []int{0, 1, 2, 4} {
+		t.Run(fmt.Sprintf("%02d", parallelism), func(t *testing.T) {
+			lock := sync.Mutex{}
+			completed := map[*ParsedFile]bool{}
+
+			err := input.WalkReverseParallel(parallelism, func(file *ParsedFile) error {
+				lock.Lock()
+				for _, other := range input {
+					for _, dep := range other.Dependencies {
+						if dep == file {
+							assert.True(t, completed[other], "%s started before %s", file.Name, other.Name)
+						}
+					}
+				}
+				lock.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				lock.Lock()
+				completed[file] = true
+				lock.Unlock()
+
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Len(t, completed, len(input))
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/avinor/tau/pkg/helpers/ui"
 )
@@ -54,6 +56,101 @@ func findFiles(path string, matchFunc func(string) bool) ([]string, error) {
 	return matches, nil
 }
 
+// expandPath returns the paths to load files from for path. If path is a glob pattern it
+// returns all paths matching pattern. When recursive is set all subdirectories of the
+// directories found are added as well, except hidden directories like .tau and .git
+func expandPath(path string, recursive bool) ([]string, error) {
+	matches := []string{path}
+
+	if isGlobPattern(path) {
+		globMatches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = []string{}
+		for _, match := range globMatches {
+			if !isHiddenMatch(path, match) {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	if !recursive {
+		return matches, nil
+	}
+
+	expanded := []string{}
+	for _, match := range matches {
+		expanded = append(expanded, match)
+
+		subDirs, err := subDirectories(match)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, subDirs...)
+	}
+
+	return expanded, nil
+}
+
+// subDirectories returns all subdirectories of dir, sorted by path. Hidden directories,
+// and all directories below them, are skipped. Returns nothing if dir is a file
+func subDirectories(dir string) ([]string, error) {
+	dirs := []string{}
+
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() || path == dir {
+			return nil
+		}
+
+		if strings.HasPrefix(fi.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		dirs = append(dirs, path)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(dirs)
+
+	return dirs, nil
+}
+
+// isHiddenMatch returns true if match has a hidden path element, like .tau, where pattern
+// does not explicitly match a hidden element. Same as a shell does not match hidden files
+// with wildcards
+func isHiddenMatch(pattern, match string) bool {
+	patternElems := strings.Split(filepath.ToSlash(pattern), "/")
+	matchElems := strings.Split(filepath.ToSlash(match), "/")
+
+	if len(patternElems) != len(matchElems) {
+		return false
+	}
+
+	for idx, elem := range matchElems {
+		if strings.HasPrefix(elem, ".") && !strings.HasPrefix(patternElems[idx], ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isGlobPattern returns true if path contains any of the special characters in glob patterns
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
 // shouldDeleteFile checks if the file should be deleted and returns true if it
 // should. It will also return an altered filename if file should be deleted.
 // Ignore altered filename if file should not be deleted
@@ -2,6 +2,9 @@ package loader
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,3 +37,73 @@ func TestShouldDeleteFile(t *testing.T) {
 		})
 	}
 }
+
+func TestIsGlobPattern(t *testing.T) {
+	tests := []struct {
+		Path     string
+		Expected bool
+	}{
+		{"/tmp/test.hcl", false},
+		{"/tmp/envs", false},
+		{"/tmp/envs/*/network.hcl", true},
+		{"/tmp/envs/dev?/network.hcl", true},
+		{"/tmp/envs/[dp]*/network.hcl", true},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, isGlobPattern(test.Path))
+		})
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, sub := range []string{"envs/dev", "envs/prod/network", "envs/.tau/module", ".git"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "envs", "dev", "network.hcl"), []byte{}, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Path      string
+		Recursive bool
+		Expected  []string
+	}{
+		{"envs", false, []string{"envs"}},
+		{"envs", true, []string{"envs", "envs/dev", "envs/prod", "envs/prod/network"}},
+		{".", true, []string{".", "envs", "envs/dev", "envs/prod", "envs/prod/network"}},
+		{"envs/*", false, []string{"envs/dev", "envs/prod"}},
+		{"envs/*", true, []string{"envs/dev", "envs/prod", "envs/prod/network"}},
+		{"envs/*/network.hcl", false, []string{"envs/dev/network.hcl"}},
+		{"envs/*/network.hcl", true, []string{"envs/dev/network.hcl"}},
+		{"envs/.*", false, []string{"envs/.tau"}},
+		{"missing/*", true, []string{}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			expanded, err := expandPath(filepath.Join(dir, test.Path), test.Recursive)
+			assert.NoError(t, err)
+
+			relative := []string{}
+			for _, path := range expanded {
+				rel, err := filepath.Rel(dir, path)
+				assert.NoError(t, err)
+
+				relative = append(relative, filepath.ToSlash(rel))
+			}
+
+			assert.Equal(t, test.Expected, relative)
+		})
+	}
+}
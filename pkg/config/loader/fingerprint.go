@@ -0,0 +1,298 @@
+package loader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/avinor/tau/pkg/config"
+	"github.com/avinor/tau/pkg/helpers/paths"
+)
+
+// Fingerprint of everything a plan is created from. It is written when a plan is created,
+// so it can be compared with current fingerprint before plan is applied.
+//
+// Sources is the sha256 hash of the configuration file and all files merged into it, keyed
+// by path relative to configuration file. ModuleCode is a hash of all terraform files in
+// module directory, except files generated by tau, and Inputs is the hash of input variables.
+// Variables is the hash of values of variables declared in file, set with --var, --var-file
+// or environment variables, and is empty if file does not declare any variables.
+// Dependencies is the hash of resolved dependency outputs and data sources, and is empty if
+// dependencies have not been resolved
+type Fingerprint struct {
+	Sources       map[string]string `json:"sources"`
+	ModuleSource  string            `json:"module_source"`
+	ModuleVersion string            `json:"module_version"`
+	ModuleCode    string            `json:"module_code"`
+	Inputs        string            `json:"inputs"`
+	Variables     string            `json:"variables"`
+	Dependencies  string            `json:"dependencies"`
+}
+
+// Fingerprint returns the current fingerprint of file
+func (p *ParsedFile) Fingerprint() (*Fingerprint, error) {
+	fp := &Fingerprint{
+		Sources:       map[string]string{},
+		ModuleSource:  p.Config.Module.Source,
+		ModuleVersion: p.Config.Module.Version,
+	}
+
+	dir := filepath.Dir(p.FullPath)
+	addSourceHashes(fp.Sources, dir, p.File, map[*config.File]bool{})
+
+	moduleCode, err := hashModuleCode(p.ModuleDir(), p.OverrideFile())
+	if err != nil {
+		return nil, err
+	}
+	fp.ModuleCode = moduleCode
+
+	if paths.IsFile(p.VariableFile()) {
+		content, err := ioutil.ReadFile(p.VariableFile())
+		if err != nil {
+			return nil, err
+		}
+
+		fp.Inputs = hashBytes(content)
+	}
+
+	variables, err := hashVariables(p.EvalContext().Variables["var"])
+	if err != nil {
+		return nil, err
+	}
+	fp.Variables = variables
+
+	dependencies, err := hashDependencies(p.EvalContext().Variables)
+	if err != nil {
+		return nil, err
+	}
+	fp.Dependencies = dependencies
+
+	return fp, nil
+}
+
+// WriteFingerprint writes current fingerprint of file to its fingerprint file
+func (p *ParsedFile) WriteFingerprint() error {
+	fp, err := p.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(fp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p.FingerprintFile(), content, os.ModePerm)
+}
+
+// ReadFingerprint reads the fingerprint written when plan was created. Returns an error
+// that satisfies os.IsNotExist if no fingerprint has been written
+func (p *ParsedFile) ReadFingerprint() (*Fingerprint, error) {
+	content, err := ioutil.ReadFile(p.FingerprintFile())
+	if err != nil {
+		return nil, err
+	}
+
+	fp := &Fingerprint{}
+	if err := json.Unmarshal(content, fp); err != nil {
+		return nil, err
+	}
+
+	return fp, nil
+}
+
+// Changes returns a description of each difference between fingerprint f and current
+// fingerprint. Returns an empty list if they are equal
+func (f *Fingerprint) Changes(current *Fingerprint) []string {
+	changes := []string{}
+
+	for _, name := range sortedStringKeys(f.Sources) {
+		hash, ok := current.Sources[name]
+
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s has been removed", name))
+		case hash != f.Sources[name]:
+			changes = append(changes, fmt.Sprintf("%s has changed", name))
+		}
+	}
+
+	for _, name := range sortedStringKeys(current.Sources) {
+		if _, ok := f.Sources[name]; !ok {
+			changes = append(changes, fmt.Sprintf("%s has been added", name))
+		}
+	}
+
+	if f.ModuleSource != current.ModuleSource {
+		changes = append(changes, fmt.Sprintf("module source changed from %s to %s", f.ModuleSource, current.ModuleSource))
+	}
+
+	if f.ModuleVersion != current.ModuleVersion {
+		changes = append(changes, fmt.Sprintf("module version changed from %s to %s",
+			valueOrNone(f.ModuleVersion), valueOrNone(current.ModuleVersion)))
+	}
+
+	if f.ModuleCode != current.ModuleCode {
+		changes = append(changes, "module code has changed")
+	}
+
+	if f.Inputs != current.Inputs {
+		changes = append(changes, "input variables have changed")
+	}
+
+	if f.Variables != current.Variables {
+		changes = append(changes, "variable values have changed")
+	}
+
+	if f.Dependencies != current.Dependencies {
+		changes = append(changes, "dependency outputs have changed")
+	}
+
+	return changes
+}
+
+// addSourceHashes adds hash of file and all its children to hashes, keyed by path relative
+// to dir
+func addSourceHashes(hashes map[string]string, dir string, file *config.File, visited map[*config.File]bool) {
+	if visited[file] {
+		return
+	}
+	visited[file] = true
+
+	name, err := filepath.Rel(dir, file.FullPath)
+	if err != nil {
+		name = file.FullPath
+	}
+
+	hashes[filepath.ToSlash(name)] = hashBytes(file.Content)
+
+	for _, child := range file.Children() {
+		addSourceHashes(hashes, dir, child, visited)
+	}
+}
+
+// hashModuleCode returns a hash of all terraform files in module directory dir, except
+// override file generated by tau. Hidden directories, like .terraform, are skipped.
+// Returns empty string if module has not been downloaded
+func hashModuleCode(dir, overrideFile string) (string, error) {
+	if !paths.IsDir(dir) {
+		return "", nil
+	}
+
+	h := sha256.New()
+
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if path != dir && strings.HasPrefix(fi.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if path == overrideFile || !isTerraformFile(fi.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isTerraformFile returns true if name is a terraform configuration file
+func isTerraformFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
+
+// hashVariables returns the hash of variable values. Returns empty string if there are no
+// variables
+func hashVariables(value cty.Value) (string, error) {
+	if value == cty.NilVal || value.IsNull() || value.LengthInt() == 0 {
+		return "", nil
+	}
+
+	content, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return "", err
+	}
+
+	return hashBytes(content), nil
+}
+
+// hashDependencies returns the hash of resolved dependency and data source values in
+// variables. Returns empty string if no dependencies have been resolved
+func hashDependencies(variables map[string]cty.Value) (string, error) {
+	values := map[string]cty.Value{}
+
+	for _, name := range []string{"dependency", "data"} {
+		if value, ok := variables[name]; ok {
+			values[name] = value
+		}
+	}
+
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	return hashVariables(cty.ObjectVal(values))
+}
+
+// hashBytes returns the sha256 hash of content
+func hashBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortedStringKeys returns keys of m sorted
+func sortedStringKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// valueOrNone returns value, or "none" if value is empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+
+	return value
+}
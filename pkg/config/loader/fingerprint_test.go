@@ -0,0 +1,234 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFingerprintChanges(t *testing.T) {
+	planned := &Fingerprint{
+		Sources:       map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"},
+		ModuleSource:  "avinor/vnet/azurerm",
+		ModuleVersion: "1.0.0",
+		ModuleCode:    "3",
+		Inputs:        "4",
+	}
+
+	tests := []struct {
+		Current  *Fingerprint
+		Expected []string
+	}{
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.0.0", "3", "4", "", ""},
+			[]string{},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "5", "common_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.0.0", "3", "4", "", ""},
+			[]string{"vnet.hcl has changed"},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "other_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.0.0", "3", "4", "", ""},
+			[]string{"common_auto.hcl has been removed", "other_auto.hcl has been added"},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.1.0", "6", "4", "", ""},
+			[]string{"module version changed from 1.0.0 to 1.1.0", "module code has changed"},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"}, "./modules/vnet", "", "3", "4", "", ""},
+			[]string{"module source changed from avinor/vnet/azurerm to ./modules/vnet", "module version changed from 1.0.0 to none"},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.0.0", "3", "", "", ""},
+			[]string{"input variables have changed"},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.0.0", "3", "4", "7", ""},
+			[]string{"variable values have changed"},
+		},
+		{
+			&Fingerprint{map[string]string{"vnet.hcl": "1", "common_auto.hcl": "2"}, "avinor/vnet/azurerm", "1.0.0", "3", "4", "", "8"},
+			[]string{"dependency outputs have changed"},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, planned.Changes(test.Current))
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name, content string) {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filename, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content := []byte("module {\n  source = \"./modules/vnet\"\n}\n")
+	writeFile("vnet.hcl", string(content))
+	writeFile("common_auto.hcl", "inputs {\n  location = \"westeurope\"\n}\n")
+	writeFile(".tau/vnet.hcl/module/main.tf", "variable \"location\" {}\n")
+
+	file, err := NewParsedFile(filepath.Join(dir, "vnet.hcl"), content, filepath.Join(dir, ".tau"), filepath.Join(dir, ".tau", "cache"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.WriteFingerprint(); err != nil {
+		t.Fatal(err)
+	}
+
+	planned, err := file.ReadFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, planned.Sources, 2)
+	assert.Contains(t, planned.Sources, "common_auto.hcl")
+	assert.Equal(t, "", planned.Inputs)
+
+	tests := []struct {
+		Name     string
+		Content  string
+		Expected []string
+	}{
+		{".tau/vnet.hcl/module/tau_override.tf", "terraform {}\n", []string{}},
+		{".tau/vnet.hcl/module/.terraform/modules/modules.json", "{}", []string{}},
+		{".tau/vnet.hcl/module/outputs.tf", "output \"id\" {}\n", []string{"module code has changed"}},
+		{".tau/vnet.hcl/module/terraform.tfvars", "location = \"westeurope\"\n", []string{"module code has changed", "input variables have changed"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			writeFile(test.Name, test.Content)
+
+			current, err := file.Fingerprint()
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, planned.Changes(current))
+		})
+	}
+}
+
+func TestFingerprintVariables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("variable \"region\" {\n  default = \"westeurope\"\n}\n\nmodule {\n  source = \"./modules/vnet\"\n}\n")
+	filename := filepath.Join(dir, "vnet.hcl")
+
+	if err := ioutil.WriteFile(filename, content, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	newFile := func(variables map[string]cty.Value) *ParsedFile {
+		file, err := NewParsedFile(filename, content, filepath.Join(dir, ".tau"), filepath.Join(dir, ".tau", "cache"), variables)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return file
+	}
+
+	planned, err := newFile(map[string]cty.Value{"region": cty.StringVal("northeurope")}).Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, "", planned.Variables)
+
+	tests := []struct {
+		Variables map[string]cty.Value
+		Expected  []string
+	}{
+		{map[string]cty.Value{"region": cty.StringVal("northeurope")}, []string{}},
+		{map[string]cty.Value{"region": cty.StringVal("westeurope")}, []string{"variable values have changed"}},
+		{nil, []string{"variable values have changed"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			current, err := newFile(test.Variables).Fingerprint()
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, planned.Changes(current))
+		})
+	}
+}
+
+func TestFingerprintDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("module {\n  source = \"./modules/aks\"\n}\n")
+	filename := filepath.Join(dir, "aks.hcl")
+
+	if err := ioutil.WriteFile(filename, content, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	newFile := func(subnet string) *ParsedFile {
+		file, err := NewParsedFile(filename, content, filepath.Join(dir, ".tau"), filepath.Join(dir, ".tau", "cache"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if subnet != "" {
+			file.AddToContext("dependency", cty.ObjectVal(map[string]cty.Value{
+				"vnet": cty.ObjectVal(map[string]cty.Value{
+					"outputs": cty.ObjectVal(map[string]cty.Value{"subnet_id": cty.StringVal(subnet)}),
+				}),
+			}))
+		}
+
+		return file
+	}
+
+	planned, err := newFile("subnet-1").Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, "", planned.Dependencies)
+
+	tests := []struct {
+		Subnet   string
+		Expected []string
+	}{
+		{"subnet-1", []string{}},
+		{"subnet-2", []string{"dependency outputs have changed"}},
+		{"", []string{"dependency outputs have changed"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			current, err := newFile(test.Subnet).Fingerprint()
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, planned.Changes(current))
+		})
+	}
+}
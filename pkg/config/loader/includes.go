@@ -0,0 +1,154 @@
+package loader
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/avinor/tau/pkg/config"
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+// addChildren adds all files that should be merged with file as children. That is files
+// included by file, then each auto import file in same directory preceded by the files it
+// includes. Included files are resolved recursively and each file is only added once, so
+// precedence when merging is, from lowest to highest: includes, auto imports and file itself.
+func addChildren(file *config.File) error {
+	added := map[string]bool{file.FullPath: true}
+
+	if err := addIncludes(file, file, []string{file.FullPath}, added); err != nil {
+		return err
+	}
+
+	autoFiles, err := AutoImports(filepath.Dir(file.FullPath))
+	if err != nil {
+		return err
+	}
+
+	for _, autoFile := range autoFiles {
+		if err := addIncludes(file, autoFile, []string{autoFile.FullPath}, added); err != nil {
+			return err
+		}
+
+		if !added[autoFile.FullPath] {
+			added[autoFile.FullPath] = true
+			file.AddChild(autoFile)
+		}
+	}
+
+	ui.Debug("merge chain for %s: %s", file.Name, strings.Join(file.MergeChain(), " < "))
+
+	return nil
+}
+
+// addIncludes adds the files included by from as children of file, each of them preceded
+// by the files they include. Chain is the list of files that lead to from, and is used to
+// detect files that include themselves.
+func addIncludes(file, from *config.File, chain []string, added map[string]bool) error {
+	includes, diags := from.IncludePaths(file.EvalContext())
+	if diags.HasErrors() {
+		return diags
+	}
+
+	for _, path := range includes {
+		for _, prev := range chain {
+			if prev == path {
+				return errors.Errorf("include cycle: %s", strings.Join(append(chain, path), " -> "))
+			}
+		}
+
+		if added[path] {
+			continue
+		}
+
+		included, err := readConfigFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to include %s", path)
+		}
+
+		included.Name = includedFileName(path, file.FullPath)
+
+		if err := addIncludes(file, included, append(chain, path), added); err != nil {
+			return err
+		}
+
+		added[path] = true
+		file.AddChild(included)
+	}
+
+	return nil
+}
+
+// includedFileName returns the name of an included file relative to directory of the file
+// including it, so files with same name in different folders can be told apart
+func includedFileName(path, filename string) string {
+	rel, err := filepath.Rel(filepath.Dir(filename), path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// includedFiles returns all sources that are included, directly or through other included
+// files, by another of sources. Included files are merged into the files including them, so
+// they should not be loaded as sources themselves. Files that include each other are still
+// returned as sources, so the include cycle is reported when loading them. Files that cannot
+// be read or parsed are ignored here, the errors are reported when the files are loaded.
+func includedFiles(sources []string) map[string]bool {
+	reachable := map[string]map[string]bool{}
+	for _, source := range sources {
+		reachable[source] = includesOf(source)
+	}
+
+	included := map[string]bool{}
+
+	for _, source := range sources {
+		for path := range reachable[source] {
+			if path == source {
+				continue
+			}
+
+			if back, ok := reachable[path]; ok && back[source] {
+				continue
+			}
+
+			included[path] = true
+		}
+	}
+
+	return included
+}
+
+// includesOf returns all files included by source, directly or through other files
+func includesOf(source string) map[string]bool {
+	includes := map[string]bool{}
+	queue := []string{source}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		content, err := ioutil.ReadFile(current)
+		if err != nil {
+			continue
+		}
+
+		file, err := config.NewFile(current, content)
+		if err != nil {
+			continue
+		}
+
+		paths, _ := file.IncludePaths(file.EvalContext())
+		for _, path := range paths {
+			if !includes[path] {
+				includes[path] = true
+				queue = append(queue, path)
+			}
+		}
+	}
+
+	return includes
+}
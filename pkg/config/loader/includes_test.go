@@ -0,0 +1,139 @@
+package loader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeIncludeFiles writes files, keyed by path relative to dir, into dir
+func writeIncludeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-includes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeIncludeFiles(t, dir, map[string]string{
+		"org.hcl": `
+			inputs {
+				owner = "org"
+				env   = "none"
+			}
+		`,
+		"prod/env.hcl": `
+			include "org" {
+				path = find_in_parent_folders("org.hcl")
+			}
+
+			inputs {
+				env = "prod"
+			}
+		`,
+		"prod/westeurope/common_auto.hcl": `
+			inputs {
+				location = "westeurope"
+				env      = "auto"
+			}
+		`,
+		"prod/westeurope/vnet.hcl": `
+			include "env" {
+				path = find_in_parent_folders("env.hcl")
+			}
+
+			module {
+				source = "avinor/vnet/azurerm"
+			}
+
+			inputs {
+				name = "vnet"
+			}
+		`,
+	})
+
+	filename := filepath.Join(dir, "prod", "westeurope", "vnet.hcl")
+	content, _ := ioutil.ReadFile(filename)
+
+	file, err := newConfigFile(filename, content, filepath.Join(dir, ".tau"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"../../org.hcl", "../env.hcl", "common_auto.hcl", "prod/westeurope/vnet.hcl"}, file.MergeChain())
+
+	config, err := file.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, len(config.Includes))
+
+	inputs, diags := config.Inputs.Config.JustAttributes()
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	for name, expected := range map[string]string{"owner": "org", "env": "auto", "location": "westeurope", "name": "vnet"} {
+		value, diags := inputs[name].Expr.Value(file.EvalContext())
+		assert.False(t, diags.HasErrors())
+		assert.Equal(t, expected, value.AsString(), name)
+	}
+
+	sources := []string{filename, filepath.Join(dir, "org.hcl"), filepath.Join(dir, "prod", "env.hcl")}
+	assert.Equal(t, map[string]bool{
+		filepath.Join(dir, "org.hcl"):         true,
+		filepath.Join(dir, "prod", "env.hcl"): true,
+	}, includedFiles(sources))
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-includes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeIncludeFiles(t, dir, map[string]string{
+		"a.hcl": `
+			include "b" {
+				path = "b.hcl"
+			}
+
+			module {
+				source = "avinor/vnet/azurerm"
+			}
+		`,
+		"b.hcl": `
+			include "a" {
+				path = "a.hcl"
+			}
+		`,
+	})
+
+	filename := filepath.Join(dir, "a.hcl")
+	content, _ := ioutil.ReadFile(filename)
+
+	_, err = newConfigFile(filename, content, filepath.Join(dir, ".tau"), nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "include cycle")
+	}
+
+	assert.Equal(t, map[string]bool{}, includedFiles([]string{filename, filepath.Join(dir, "b.hcl")}))
+}
@@ -8,9 +8,12 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+
 	"github.com/avinor/tau/pkg/getter"
 	"github.com/avinor/tau/pkg/helpers/paths"
-	"github.com/pkg/errors"
+	"github.com/avinor/tau/pkg/helpers/ui"
 )
 
 var (
@@ -57,6 +60,12 @@ type Options struct {
 
 	// MaxDepth to search for dependencies. Should be enough with 1.
 	MaxDepth int
+
+	// Recursive loads files from all subdirectories of directories in path as well
+	Recursive bool
+
+	// Variables are values of variables declared in files, keyed by variable name
+	Variables map[string]cty.Value
 }
 
 // New creates a new loader client with options
@@ -76,29 +85,78 @@ func New(options *Options) *Loader {
 }
 
 // Load files from path and return list of all ParsedFile found at path. Path can either
-// be a single file or a directory, in which case it will load all files found in
-// directory.
-func (l *Loader) Load(paths []string) (ParsedFileCollection, error) {
+// be a single file, a directory, in which case it will load all files found in
+// directory, or a glob pattern matching files and directories. Subdirectories are only
+// loaded if Recursive option is set. Each file is only returned once, even if matched
+// by several paths, and files included by other files found are not loaded.
+func (l *Loader) Load(srcs []string) (ParsedFileCollection, error) {
+	sources, err := l.findSources(srcs, l.options.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
 	files := make([]*ParsedFile, 0)
 
-	for _, path := range paths {
+	for _, source := range sources {
+		file, err := l.getParsedFile(source)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	if err := l.loadDependencies(files, 0); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// findSources returns all source files in paths, in the order they are found. Each file is
+// only returned once, and files included by other files found are not returned.
+func (l *Loader) findSources(srcs []string, recursive bool) ([]string, error) {
+	sources := []string{}
+	found := map[string]bool{}
+
+	for _, path := range srcs {
 		if path == "" {
 			return nil, sourcePathNotFoundError
 		}
 
-		loaded, err := l.loadFromPath(path)
+		expanded, err := expandPath(paths.Abs(l.options.WorkingDirectory, path), recursive)
 		if err != nil {
 			return nil, err
 		}
 
-		files = append(files, loaded...)
+		for _, p := range expanded {
+			files, err := findFiles(p, moduleMatchFunc)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, file := range files {
+				if !found[file] {
+					found[file] = true
+					sources = append(sources, file)
+				}
+			}
+		}
 	}
 
-	if err := l.loadDependencies(files, 0); err != nil {
-		return nil, err
+	included := includedFiles(sources)
+	selected := []string{}
+
+	for _, source := range sources {
+		if included[source] {
+			ui.Debug("%s is included by another file, not loading it as source", source)
+			continue
+		}
+
+		selected = append(selected, source)
 	}
 
-	return files, nil
+	return selected, nil
 }
 
 // loadFromPath loads all files matching path pattern and returns the ParsedFile
@@ -134,35 +192,70 @@ func (l *Loader) loadDependencies(files []*ParsedFile, depth int) error {
 	}
 
 	for _, file := range files {
-		dir := filepath.Dir(file.FullPath)
-
-		for _, dep := range file.Config.Dependencies {
-			path := filepath.Join(dir, dep.Source)
-			deps, err := l.loadFromPath(path)
+		deps, err := l.loadFileDependencies(file)
+		if err != nil {
+			return err
+		}
 
-			if err != nil {
-				return err
-			}
+		if err := l.loadDependencies(deps, depth+1); err != nil {
+			return err
+		}
+	}
 
-			if len(deps) > 1 {
-				return dependencySingleFileError
-			}
+	return nil
+}
 
-			if len(deps) == 0 {
-				continue
-			}
+// loadAllDependencies loads the dependencies of files recursively, without the depth limit
+// of loadDependencies, so the full dependency tree of files is loaded
+func (l *Loader) loadAllDependencies(files []*ParsedFile, visited map[*ParsedFile]bool) error {
+	for _, file := range files {
+		if visited[file] {
+			continue
+		}
+		visited[file] = true
 
-			file.Dependencies[dep.Name] = deps[0]
+		deps, err := l.loadFileDependencies(file)
+		if err != nil {
+			return err
+		}
 
-			if err := l.loadDependencies(deps, depth+1); err != nil {
-				return err
-			}
+		if err := l.loadAllDependencies(deps, visited); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// loadFileDependencies loads the dependencies of a single file into its dependency map and
+// returns them
+func (l *Loader) loadFileDependencies(file *ParsedFile) ([]*ParsedFile, error) {
+	dir := filepath.Dir(file.FullPath)
+	loaded := []*ParsedFile{}
+
+	for _, dep := range file.Config.Dependencies {
+		path := filepath.Join(dir, dep.Source)
+		deps, err := l.loadFromPath(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(deps) > 1 {
+			return nil, dependencySingleFileError
+		}
+
+		if len(deps) == 0 {
+			continue
+		}
+
+		file.Dependencies[dep.Name] = deps[0]
+		loaded = append(loaded, deps[0])
+	}
+
+	return loaded, nil
+}
+
 // getParsedFile checks if the file has already been parsed and returns previous parsed file
 // or loads the file if not already loaded. Next time this is called with same source file
 // it will return a reference to the previous loaded file.
@@ -180,7 +273,7 @@ func (l *Loader) getParsedFile(file string) (*ParsedFile, error) {
 		return nil, err
 	}
 
-	parsed, err := NewParsedFile(file, content, l.options.TauDirectory, l.options.CacheDirectory)
+	parsed, err := NewParsedFile(file, content, l.options.TauDirectory, l.options.CacheDirectory, l.options.Variables)
 	if err != nil {
 		return nil, err
 	}
@@ -1,13 +1,16 @@
 package loader
 
 import (
+	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/zclconf/go-cty/cty"
 
 	"github.com/avinor/tau/pkg/config"
 	"github.com/avinor/tau/pkg/helpers/paths"
+	"github.com/avinor/tau/pkg/helpers/ui"
 )
 
 var (
@@ -19,6 +22,10 @@ var (
 // Config() function to return configuration. For parsed file the Config attribute should
 // be used instead as that prevents it from parsing the config file multiple times.
 // They will both return same result though.
+//
+// Log should be used for all output that belongs to this file. By default it prints same
+// as the ui package, but when processing files in parallel it can be replaced with a scope
+// that prefixes all lines with file name.
 type ParsedFile struct {
 	*config.File
 
@@ -27,13 +34,15 @@ type ParsedFile struct {
 	Env          map[string]string
 	Dependencies map[string]*ParsedFile
 	ShouldDelete bool
+	Log          *ui.Scope
 
 	moduleDir string
 }
 
 // NewParsedFile creates a new parsed file from input parameters. It does not try to read the file
-// on disk, but filename has to be an absolute path to file.
-func NewParsedFile(filename string, content []byte, tauDir, cacheDir string) (*ParsedFile, error) {
+// on disk, but filename has to be an absolute path to file. Variables are the values of variables
+// declared in file, it can be nil if no values are set.
+func NewParsedFile(filename string, content []byte, tauDir, cacheDir string, variables map[string]cty.Value) (*ParsedFile, error) {
 	if !filepath.IsAbs(filename) {
 		return nil, filePathMustBeAbsError
 	}
@@ -43,21 +52,13 @@ func NewParsedFile(filename string, content []byte, tauDir, cacheDir string) (*P
 		filename = altered
 	}
 
-	configFile, err := config.NewFile(filename, content)
+	configFile, err := newConfigFile(filename, content, tauDir, variables)
 	if err != nil {
 		return nil, err
 	}
 	tempDir := paths.Join(tauDir, configFile.Name)
 	moduleDir := paths.Join(tempDir, "module")
 
-	configFile.AddToContext("module", cty.ObjectVal(map[string]cty.Value{
-		"path": cty.StringVal(moduleDir),
-	}))
-
-	if err := AddAutoImports(configFile); err != nil {
-		return nil, err
-	}
-
 	cfg, err := configFile.Config()
 	if err != nil {
 		return nil, err
@@ -81,10 +82,118 @@ func NewParsedFile(filename string, content []byte, tauDir, cacheDir string) (*P
 		Env:          env,
 		Dependencies: map[string]*ParsedFile{},
 		ShouldDelete: del,
+		Log:          ui.NewScope(""),
 		moduleDir:    moduleDir,
 	}, nil
 }
 
+// newConfigFile creates the config file for filename. It adds the variables tau defines
+// to evaluation context, values of declared variables and all included files and auto imports
+// as children of file.
+func newConfigFile(filename string, content []byte, tauDir string, variables map[string]cty.Value) (*config.File, error) {
+	configFile, err := config.NewFile(filename, content)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile.SetVariableValues(variables)
+
+	configFile.Name = parsedFileName(configFile.FullPath, tauDir)
+
+	moduleDir := paths.Join(tauDir, configFile.Name, "module")
+
+	configFile.AddToContext("module", cty.ObjectVal(map[string]cty.Value{
+		"path": cty.StringVal(moduleDir),
+	}))
+
+	if err := addChildren(configFile); err != nil {
+		return nil, err
+	}
+
+	return configFile, nil
+}
+
+// parsedFileName returns the name of file. Files in subdirectories of the directory that
+// contains tau directory are named by their relative path, so files with same name in
+// different directories get separate temporary directories. Other files are named by
+// their base name.
+func parsedFileName(filename, tauDir string) string {
+	name := filepath.Base(filename)
+
+	rel, err := filepath.Rel(filepath.Dir(tauDir), filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return name
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// OrphanedDirs returns the temporary directories in tauDir where the source file no longer
+// exists. Each directory is mapped back to its source file using the same naming as
+// parsedFileName, relative to the directory that contains tauDir. A source file prefixed
+// delete or destroy still owns the directory, as it is needed to destroy the deployment.
+func OrphanedDirs(tauDir string) ([]string, error) {
+	return orphanedDirs(tauDir, filepath.Dir(tauDir))
+}
+
+// orphanedDirs returns directories in dir whose source file does not exist in sourceDir.
+// Directories not named like a source file are subdirectories of files in subdirectories,
+// and are searched recursively.
+func orphanedDirs(dir, sourceDir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	orphaned := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		source := filepath.Join(sourceDir, entry.Name())
+
+		if !moduleRegexp.MatchString(entry.Name()) {
+			sub, err := orphanedDirs(path, source)
+			if err != nil {
+				return nil, err
+			}
+
+			orphaned = append(orphaned, sub...)
+			continue
+		}
+
+		if !sourceFileExists(source) {
+			orphaned = append(orphaned, path)
+		}
+	}
+
+	return orphaned, nil
+}
+
+// sourceFileExists returns true if filename exists, or a file that deletes it exists
+func sourceFileExists(filename string) bool {
+	if paths.IsFile(filename) {
+		return true
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(filename), entry.Name())
+
+		if del, altered := shouldDeleteFile(path); del && altered == filename && !entry.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ModuleDir returns the module directory where source module is downloaded
 func (p ParsedFile) ModuleDir() string {
 	return p.moduleDir
@@ -106,6 +215,24 @@ func (p ParsedFile) PlanFile() string {
 	return paths.Join(p.ModuleDir(), "tau.tfplan")
 }
 
+// DriftPlanFile returns name of plan file when checking for drift with `tau drift`. It is
+// separate from PlanFile so checking for drift does not replace a plan waiting to be applied.
+func (p ParsedFile) DriftPlanFile() string {
+	return paths.Join(p.ModuleDir(), "tau-drift.tfplan")
+}
+
+// FingerprintFile returns name of file with fingerprint of everything plan file was
+// created from. It is next to plan file so it is moved together with the plan.
+func (p ParsedFile) FingerprintFile() string {
+	return paths.Join(p.ModuleDir(), "tau.tfplan.fingerprint")
+}
+
+// LockFile returns name of lock file that is held while running commands on file. It is
+// next to temp directory so removing temp directory does not remove the lock.
+func (p ParsedFile) LockFile() string {
+	return p.TempDir + ".lock"
+}
+
 // VariableFile returns name of input variable file
 func (p ParsedFile) VariableFile() string {
 	return paths.Join(p.ModuleDir(), "terraform.tfvars")
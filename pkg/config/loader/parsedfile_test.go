@@ -0,0 +1,74 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsedFileName(t *testing.T) {
+	tests := []struct {
+		Filename string
+		TauDir   string
+		Expected string
+	}{
+		{"/repo/vnet.hcl", "/repo/.tau", "vnet.hcl"},
+		{"/repo/envs/dev/vnet.hcl", "/repo/.tau", "envs/dev/vnet.hcl"},
+		{"/repo/envs/dev/vnet.hcl", "/repo/envs/dev/.tau", "vnet.hcl"},
+		{"/repo/shared/logs.hcl", "/repo/envs/dev/.tau", "logs.hcl"},
+		{"/repo/vnet.hcl", "", "vnet.hcl"},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, parsedFileName(test.Filename, test.TauDir))
+		})
+	}
+}
+
+func TestOrphanedDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-orphaned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tauDir := filepath.Join(dir, ".tau")
+
+	for _, name := range []string{"vnet.hcl", "envs/dev/vnet.hcl", "envs/dev/delete_aks.hcl"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(""), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"vnet.hcl/module", "removed.hcl/module", "envs/dev/vnet.hcl", "envs/dev/aks.hcl",
+		"envs/prod/vnet.hcl/module"} {
+		if err := os.MkdirAll(filepath.Join(tauDir, filepath.FromSlash(name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(tauDir, "removed.hcl.lock"), []byte(""), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	orphaned, err := OrphanedDirs(tauDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		filepath.Join(tauDir, "envs", "prod", "vnet.hcl"),
+		filepath.Join(tauDir, "removed.hcl"),
+	}, orphaned)
+}
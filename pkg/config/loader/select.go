@@ -0,0 +1,130 @@
+package loader
+
+import (
+	"path/filepath"
+
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+// SelectOptions describes how to expand or restrict a collection of loaded files
+type SelectOptions struct {
+	// IncludeDependencies adds all dependencies of selected files, also dependencies of
+	// dependencies, regardless of max depth
+	IncludeDependencies bool
+
+	// IncludeDependents adds all files that depend on selected files. Dependents are
+	// searched for in working directory and all its subdirectories.
+	IncludeDependents bool
+
+	// Exclude is a list of files or directories to remove from selection
+	Exclude []string
+
+	// ChangedSince is a git revision. Only files that have changed since revision are
+	// selected, see Loader.changedSince. It is applied before expanding selection.
+	ChangedSince string
+}
+
+// Select expands or restricts files depending on options. Files added are processed the
+// same way as files loaded with Load. Changed files are selected first, so dependencies and
+// dependents of changed files are added. Exclude is applied last, so excluded files are never
+// part of the result, even if they are a dependency of another selected file.
+func (l *Loader) Select(files ParsedFileCollection, options *SelectOptions) (ParsedFileCollection, error) {
+	if options == nil {
+		return files, nil
+	}
+
+	if options.ChangedSince != "" {
+		changed, err := l.changedSince(options.ChangedSince)
+		if err != nil {
+			return nil, err
+		}
+
+		selected := ParsedFileCollection{}
+		for _, file := range files {
+			if isChanged(l.sourcePaths(file), changed) {
+				selected = append(selected, file)
+			}
+		}
+
+		files = selected
+	}
+
+	if options.IncludeDependents {
+		candidates, err := l.loadDependents(files)
+		if err != nil {
+			return nil, err
+		}
+
+		files = files.WithDependents(candidates)
+	}
+
+	if options.IncludeDependencies {
+		if err := l.loadAllDependencies(files, map[*ParsedFile]bool{}); err != nil {
+			return nil, err
+		}
+
+		files = files.WithDependencies()
+	}
+
+	if len(options.Exclude) > 0 {
+		excluded := []string{}
+
+		for _, path := range options.Exclude {
+			sources, err := findFiles(l.abs(path), moduleMatchFunc)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, source := range sources {
+				if del, altered := shouldDeleteFile(source); del {
+					source = altered
+				}
+
+				excluded = append(excluded, source)
+			}
+		}
+
+		files = files.Without(excluded)
+	}
+
+	return files, nil
+}
+
+// loadDependents loads all files in working directory and its subdirectories, with
+// dependencies, so they can be checked if they depend on any of the files. Files that fail
+// to load are skipped with a warning, they cannot be checked but should not stop selection.
+// Selected files have already been loaded, so errors in them have been reported by Load.
+func (l *Loader) loadDependents(files ParsedFileCollection) (ParsedFileCollection, error) {
+	sources, err := l.findSources([]string{l.options.WorkingDirectory}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := ParsedFileCollection{}
+	for _, source := range sources {
+		file, err := l.getParsedFile(source)
+		if err == nil {
+			err = l.loadDependencies([]*ParsedFile{file}, 0)
+		}
+
+		if err != nil {
+			ui.Warn("Could not load %s when searching for dependents, skipping it: %s", l.relative(source), err)
+			continue
+		}
+
+		candidates = append(candidates, file)
+	}
+
+	return candidates, nil
+}
+
+// relative returns path relative to working directory, or path itself if it is not
+// possible
+func (l *Loader) relative(path string) string {
+	rel, err := filepath.Rel(l.options.WorkingDirectory, path)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(rel)
+}
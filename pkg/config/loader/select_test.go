@@ -0,0 +1,74 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tau-select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	module := "module {\n  source = \"./modules/%s\"\n}\n"
+	dependency := "dependency \"%s\" {\n  source = \"%s\"\n}\n"
+
+	writeIncludeFiles(t, dir, map[string]string{
+		"core/rg.hcl":    fmt.Sprintf(module, "rg"),
+		"core/vnet.hcl":  fmt.Sprintf(module, "vnet") + fmt.Sprintf(dependency, "rg", "rg.hcl"),
+		"core/aks.hcl":   fmt.Sprintf(module, "aks") + fmt.Sprintf(dependency, "vnet", "vnet.hcl"),
+		"apps/app.hcl":   fmt.Sprintf(module, "app") + fmt.Sprintf(dependency, "aks", "../core/aks.hcl"),
+		"apps/other.hcl": fmt.Sprintf(module, "other"),
+		"broken/bad.hcl": "module {\n  source = \n",
+		"broken/dep.hcl": fmt.Sprintf(module, "dep") + fmt.Sprintf(dependency, "bad", "bad.hcl"),
+	})
+
+	tests := []struct {
+		Path     string
+		Options  *SelectOptions
+		Expected []string
+	}{
+		{"core/aks.hcl", &SelectOptions{}, []string{"core/aks.hcl"}},
+		{"core/aks.hcl", &SelectOptions{IncludeDependencies: true}, []string{"core/aks.hcl", "core/rg.hcl", "core/vnet.hcl"}},
+		{"core/rg.hcl", &SelectOptions{IncludeDependents: true}, []string{"apps/app.hcl", "core/aks.hcl", "core/rg.hcl", "core/vnet.hcl"}},
+		{"apps/app.hcl", &SelectOptions{IncludeDependencies: true, Exclude: []string{"core/rg.hcl"}}, []string{"apps/app.hcl", "core/aks.hcl", "core/vnet.hcl"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			l := New(&Options{
+				WorkingDirectory: dir,
+				TauDirectory:     filepath.Join(dir, ".tau"),
+				CacheDirectory:   filepath.Join(dir, ".tau", "cache"),
+				MaxDepth:         1,
+			})
+
+			files, err := l.Load([]string{test.Path})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			selected, err := l.Select(files, test.Options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := []string{}
+			for _, file := range selected {
+				rel, _ := filepath.Rel(dir, file.FullPath)
+				names = append(names, filepath.ToSlash(rel))
+			}
+			sort.Strings(names)
+
+			assert.Equal(t, test.Expected, names)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/avinor/tau/pkg/helpers/paths"
+)
+
+// Validate finds all files in paths, the same way as Load, and validates each of them.
+// Unlike Load it does not stop on the first invalid file, but returns all problems found
+// as diagnostics. It checks that dependencies resolve to a single file, but does not
+// validate the dependencies unless they are also found in paths. Files included by other
+// files found are not validated on their own.
+//
+// Returns the number of files validated together with diagnostics.
+func (l *Loader) Validate(paths []string) (int, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	validated := 0
+	sources := []string{}
+	seen := map[string]bool{}
+
+	for _, path := range paths {
+		if path == "" {
+			diags = append(diags, errorDiagnostic("Invalid file argument", sourcePathNotFoundError, nil))
+			continue
+		}
+
+		expanded, err := expandPath(l.abs(path), l.options.Recursive)
+		if err != nil {
+			diags = append(diags, errorDiagnostic("Unable to find files", err, nil))
+			continue
+		}
+
+		for _, p := range expanded {
+			files, err := findFiles(p, moduleMatchFunc)
+			if err != nil {
+				diags = append(diags, errorDiagnostic("Unable to find files", err, nil))
+				continue
+			}
+
+			for _, file := range files {
+				if !seen[file] {
+					seen[file] = true
+					sources = append(sources, file)
+				}
+			}
+		}
+	}
+
+	included := includedFiles(sources)
+
+	for _, source := range sources {
+		if included[source] {
+			continue
+		}
+
+		validated++
+		diags = append(diags, l.validateFile(source)...)
+	}
+
+	return validated, sortDiagnostics(uniqueDiagnostics(diags))
+}
+
+// validateFile validates a single file and checks that all its dependencies can be resolved
+func (l *Loader) validateFile(filename string) hcl.Diagnostics {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return hcl.Diagnostics{errorDiagnostic("Unable to read file", err, nil)}
+	}
+
+	if del, altered := shouldDeleteFile(filename); del {
+		filename = altered
+	}
+
+	configFile, err := newConfigFile(filename, content, l.options.TauDirectory, l.options.Variables)
+	if err != nil {
+		return hcl.Diagnostics{errorDiagnostic("Unable to read file", err, nil)}
+	}
+
+	diags := configFile.Validate()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	cfg, err := configFile.Config()
+	if err != nil {
+		return append(diags, errorDiagnostic("Invalid configuration", err, configFile.BlockDefRange("")))
+	}
+
+	dir := filepath.Dir(configFile.FullPath)
+
+	for _, dep := range cfg.Dependencies {
+		subject := configFile.BlockDefRange("dependency", dep.Name)
+
+		deps, err := findFiles(filepath.Join(dir, dep.Source), moduleMatchFunc)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Dependency source not found",
+				Detail:   fmt.Sprintf("Source %q of dependency %q could not be found: %s.", dep.Source, dep.Name, err),
+				Subject:  subject,
+			})
+			continue
+		}
+
+		if len(deps) > 1 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid dependency source",
+				Detail:   fmt.Sprintf("Source %q of dependency %q: %s.", dep.Source, dep.Name, dependencySingleFileError),
+				Subject:  subject,
+			})
+		}
+
+		if len(deps) == 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Dependency has no source file",
+				Detail:   fmt.Sprintf("Source %q of dependency %q does not contain any tau files, dependency is ignored.", dep.Source, dep.Name),
+				Subject:  subject,
+			})
+		}
+	}
+
+	return diags
+}
+
+// abs returns the absolute path of path relative to working directory
+func (l *Loader) abs(path string) string {
+	return paths.Abs(l.options.WorkingDirectory, path)
+}
+
+// errorDiagnostic returns an error diagnostic with err as detail
+func errorDiagnostic(summary string, err error, subject *hcl.Range) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  summary,
+		Detail:   err.Error(),
+		Subject:  subject,
+	}
+}
+
+// uniqueDiagnostics removes duplicate diagnostics. Same diagnostic can be reported several
+// times when the problem is in an auto import file that is included in many files.
+func uniqueDiagnostics(diags hcl.Diagnostics) hcl.Diagnostics {
+	unique := hcl.Diagnostics{}
+	seen := map[string]bool{}
+
+	for _, diag := range diags {
+		key := diag.Error()
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		unique = append(unique, diag)
+	}
+
+	return unique
+}
+
+// sortDiagnostics sorts diagnostics by filename and position, so they are reported in
+// same order as they appear in files. Diagnostics without subject are sorted first.
+func sortDiagnostics(diags hcl.Diagnostics) hcl.Diagnostics {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Subject, diags[j].Subject
+
+		switch {
+		case a == nil || b == nil:
+			return a == nil && b != nil
+		case a.Filename != b.Filename:
+			return a.Filename < b.Filename
+		default:
+			return a.Start.Byte < b.Start.Byte
+		}
+	})
+
+	return diags
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/avinor/tau/pkg/config/comp"
+)
+
+var (
+	// localsSchema is the schema to read only locals blocks from a file
+	localsSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "locals"},
+		},
+	}
+)
+
+// Locals are named values that can be referenced as local.NAME in all other blocks. They are
+// evaluated before rest of configuration is decoded, and can reference other locals and the
+// source variables. Locals in file take precedence over locals with same name in auto imports.
+type Locals struct {
+	Config hcl.Body `hcl:",remain"`
+
+	comp.Remainer
+}
+
+// mergeLocals merges the locals arrays into destination config. Locals have already been
+// evaluated when merging, so they are only kept for reference
+func mergeLocals(dest *Config, srcs []*Config) error {
+	for _, src := range srcs {
+		dest.Locals = append(dest.Locals, src.Locals...)
+	}
+
+	return nil
+}
+
+// evaluateLocals evaluates locals in file and all children, and adds them to evaluation
+// context as local.NAME. Locals are evaluated in order of their references to each other,
+// a local cannot reference itself, directly or through other locals
+func (f *File) evaluateLocals() hcl.Diagnostics {
+	attrs, diags := f.localAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	values := map[string]cty.Value{}
+	evaluating := map[string]bool{}
+
+	var evaluate func(attr *hcl.Attribute) hcl.Diagnostics
+	evaluate = func(attr *hcl.Attribute) hcl.Diagnostics {
+		if _, done := values[attr.Name]; done {
+			return nil
+		}
+
+		if evaluating[attr.Name] {
+			return hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Cycle in local values",
+				Detail:   fmt.Sprintf("Local value %q refers to itself, directly or through other local values.", attr.Name),
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+
+		evaluating[attr.Name] = true
+		defer delete(evaluating, attr.Name)
+
+		for _, t := range attr.Expr.Variables() {
+			if t.RootName() != "local" {
+				continue
+			}
+
+			name, ok := traversalAttrName(t, 1)
+			if !ok {
+				continue
+			}
+
+			ref, declared := attrs[name]
+			if !declared {
+				subject := t.SourceRange()
+				return hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Reference to undeclared local value",
+					Detail:   fmt.Sprintf("A local value named %q has not been declared.", name),
+					Subject:  &subject,
+				}}
+			}
+
+			if diags := evaluate(ref); diags.HasErrors() {
+				return diags
+			}
+		}
+
+		f.context.Variables["local"] = cty.ObjectVal(values)
+
+		value, diags := attr.Expr.Value(f.context)
+		if diags.HasErrors() {
+			return diags
+		}
+
+		values[attr.Name] = value
+
+		return diags
+	}
+
+	names := []string{}
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		diags = append(diags, evaluate(attrs[name])...)
+
+		if diags.HasErrors() {
+			return diags
+		}
+	}
+
+	f.context.Variables["local"] = cty.ObjectVal(values)
+
+	return diags
+}
+
+// localAttributes returns all attributes in locals blocks of file and children. Attributes
+// in file override attributes in children, same as when merging inputs
+func (f *File) localAttributes() (map[string]*hcl.Attribute, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	attrs := map[string]*hcl.Attribute{}
+
+	for _, file := range append(f.children, f) {
+		hclFile, fileDiags := parser.ParseHCL(file.Content, file.FullPath)
+		diags = append(diags, fileDiags...)
+
+		if fileDiags.HasErrors() {
+			continue
+		}
+
+		content, _, contentDiags := hclFile.Body.PartialContent(localsSchema)
+		diags = append(diags, contentDiags...)
+
+		if contentDiags.HasErrors() {
+			continue
+		}
+
+		for _, block := range content.Blocks {
+			blockAttrs, attrDiags := block.Body.JustAttributes()
+			diags = append(diags, attrDiags...)
+
+			for name, attr := range blockAttrs {
+				attrs[name] = attr
+			}
+		}
+	}
+
+	return attrs, diags
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	localsTest1 = `
+		locals {
+			prefix = "tau-${source.name}"
+			name   = "${local.prefix}-rg"
+		}
+
+		module {
+			source = "./"
+		}
+	`
+
+	localsTest2 = `
+		locals {
+			prefix = "common"
+			tags = {
+				owner = "platform"
+			}
+		}
+	`
+
+	localsTest3 = `
+		locals {
+			a = local.b
+			b = local.a
+		}
+	`
+
+	localsTest4 = `
+		locals {
+			a = local.missing
+		}
+	`
+
+	localsTest5 = `
+		locals {
+			a = "a"
+		}
+
+		locals {
+			b = upper(local.a)
+		}
+	`
+
+	localsTest6 = `
+		locals {
+			source = "./modules/${source.name}"
+		}
+
+		module {
+			source = local.source
+		}
+
+		inputs {
+			name = local.source
+		}
+	`
+)
+
+func TestEvaluateLocals(t *testing.T) {
+	tests := []struct {
+		Content  string
+		Children []string
+		Expected map[string]cty.Value
+		Error    string
+	}{
+		{
+			localsTest1,
+			nil,
+			map[string]cty.Value{
+				"prefix": cty.StringVal("tau-vnet"),
+				"name":   cty.StringVal("tau-vnet-rg"),
+			},
+			"",
+		},
+		{
+			localsTest1,
+			[]string{localsTest2},
+			map[string]cty.Value{
+				"prefix": cty.StringVal("tau-vnet"),
+				"name":   cty.StringVal("tau-vnet-rg"),
+				"tags":   cty.ObjectVal(map[string]cty.Value{"owner": cty.StringVal("platform")}),
+			},
+			"",
+		},
+		{
+			localsTest2,
+			nil,
+			map[string]cty.Value{
+				"prefix": cty.StringVal("common"),
+				"tags":   cty.ObjectVal(map[string]cty.Value{"owner": cty.StringVal("platform")}),
+			},
+			"",
+		},
+		{
+			localsTest3,
+			nil,
+			nil,
+			"Cycle in local values",
+		},
+		{
+			localsTest4,
+			nil,
+			nil,
+			"Reference to undeclared local value",
+		},
+		{
+			localsTest5,
+			nil,
+			map[string]cty.Value{
+				"a": cty.StringVal("a"),
+				"b": cty.StringVal("A"),
+			},
+			"",
+		},
+		{
+			"module {\n source = \"./\"\n}\n",
+			nil,
+			map[string]cty.Value{},
+			"",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			file, _ := NewFile(fmt.Sprintf("/locals%02d/vnet.hcl", i), []byte(test.Content))
+			for idx, child := range test.Children {
+				childFile, _ := NewFile(fmt.Sprintf("/locals%02d/child%v_auto.hcl", i, idx), []byte(child))
+				file.AddChild(childFile)
+			}
+
+			diags := file.evaluateLocals()
+
+			if test.Error != "" {
+				assert.Contains(t, diags.Error(), test.Error)
+				return
+			}
+
+			assert.False(t, diags.HasErrors(), diags.Error())
+
+			locals := file.EvalContext().Variables["local"]
+			assert.Equal(t, len(test.Expected), len(locals.AsValueMap()))
+
+			for name, expected := range test.Expected {
+				assert.True(t, expected.RawEquals(locals.GetAttr(name)), name)
+			}
+		})
+	}
+}
+
+func TestLocalsInConfig(t *testing.T) {
+	file, _ := NewFile("/locals/vnet.hcl", []byte(localsTest6))
+
+	config, err := file.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "./modules/vnet", config.Module.Source)
+
+	inputs, diags := config.Inputs.Config.JustAttributes()
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	value, diags := inputs["name"].Expr.Value(file.EvalContext())
+	assert.False(t, diags.HasErrors())
+	assert.Equal(t, "./modules/vnet", value.AsString())
+}
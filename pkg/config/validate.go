@@ -0,0 +1,389 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	hclcontext "github.com/avinor/tau/pkg/helpers/hcl"
+)
+
+// Validate parses the file and all children and validates the merged configuration. Unlike
+// Config.Validate it does not stop on first error, it returns all problems found as
+// diagnostics with source ranges where possible.
+//
+// Inputs are not evaluated as they can reference dependencies and data sources that are not
+// resolved yet. Instead it checks that all references in inputs point to a declared
+// dependency, data source or a variable in evaluation context.
+func (f *File) Validate() hcl.Diagnostics {
+	diags := f.evaluateContext()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	configs := []*Config{}
+
+	for _, file := range append(f.children, f) {
+		config, fileDiags := file.decode(f.context)
+		diags = append(diags, fileDiags...)
+
+		if config != nil {
+			configs = append(configs, config)
+		}
+	}
+
+	// Do not validate merged config if any of the files are invalid, it will most likely
+	// just report errors caused by missing configuration
+	if diags.HasErrors() {
+		return diags
+	}
+
+	config := &Config{}
+	if err := config.Merge(configs); err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unable to merge configuration",
+			Detail:   err.Error(),
+			Subject:  f.BlockDefRange(""),
+		})
+	}
+
+	diags = append(diags, f.validateModule(config)...)
+	diags = append(diags, f.validateBackend(config.Backend)...)
+	diags = append(diags, f.validateDependencies(config.Dependencies)...)
+	diags = append(diags, f.validateDatas(config.Datas)...)
+	diags = append(diags, f.validateHooks(config.Hooks)...)
+	diags = append(diags, f.validateEnvironment(config.Environment)...)
+	diags = append(diags, f.validateInputs(config)...)
+
+	return diags
+}
+
+// BlockDefRange returns the range where block of type blockType is defined, in this file or
+// any of the children. Blocks in this file are preferred as they take precedence when merging.
+// Labels are compared in order, and only the labels given have to match. If block is not
+// found it will return a range pointing to start of this file.
+func (f *File) BlockDefRange(blockType string, labels ...string) *hcl.Range {
+	files := []*File{f}
+	for i := len(f.children) - 1; i >= 0; i-- {
+		files = append(files, f.children[i])
+	}
+
+	for _, file := range files {
+		if blockType == "" {
+			break
+		}
+
+		hclFile, diags := parser.ParseHCL(file.Content, file.FullPath)
+		if diags.HasErrors() {
+			continue
+		}
+
+		body, ok := hclFile.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type == blockType && labelsMatch(block.Labels, labels) {
+				defRange := block.DefRange()
+				return &defRange
+			}
+		}
+	}
+
+	return &hcl.Range{
+		Filename: f.FullPath,
+		Start:    hcl.InitialPos,
+		End:      hcl.InitialPos,
+	}
+}
+
+// validateModule checks that module block is defined and has a source
+func (f *File) validateModule(config *Config) hcl.Diagnostics {
+	if config.Module == nil {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Missing module block",
+			Detail:   moduleRequired.Error(),
+			Subject:  f.BlockDefRange(""),
+		}}
+	}
+
+	if config.Module.Source == "" {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Missing module source",
+			Detail:   "The module source cannot be empty.",
+			Subject:  f.BlockDefRange("module"),
+		}}
+	}
+
+	return nil
+}
+
+// validateBackend checks that backend only contains attributes and that they can be
+// evaluated in context of file
+func (f *File) validateBackend(backend *Backend) hcl.Diagnostics {
+	if backend == nil {
+		return nil
+	}
+
+	values := map[string]cty.Value{}
+	return gohcl.DecodeBody(backend.Config, f.context, &values)
+}
+
+// validateDependencies checks that all dependencies have a source and that backend
+// overrides only contain attributes
+func (f *File) validateDependencies(deps []*Dependency) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, dep := range deps {
+		if valid, err := dep.Validate(); !valid {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid dependency block",
+				Detail:   fmt.Sprintf("Dependency %q is invalid: %s.", dep.Name, err),
+				Subject:  f.BlockDefRange("dependency", dep.Name),
+			})
+		}
+
+		if dep.Backend != nil {
+			diags = append(diags, f.validateBackend(dep.Backend)...)
+		}
+	}
+
+	return diags
+}
+
+// validateDatas checks that data sources can be written to terraform. Data sources are
+// copied as is, so they cannot reference any variables
+func (f *File) validateDatas(datas []*Data) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, data := range datas {
+		body, ok := data.Config.(*hclsyntax.Body)
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate data block",
+				Detail: fmt.Sprintf(
+					"Data source %s.%s is defined in more than one file, data sources cannot be merged.",
+					data.Type, data.Name,
+				),
+				Subject: f.BlockDefRange("data", data.Type, data.Name),
+			})
+			continue
+		}
+
+		diags = append(diags, validateDataBody(body)...)
+	}
+
+	return diags
+}
+
+// validateHooks checks that all hooks are valid
+func (f *File) validateHooks(hooks []*Hook) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, hook := range hooks {
+		if valid, err := hook.Validate(); !valid {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid hook block",
+				Detail:   fmt.Sprintf("Hook %q is invalid: %s.", hook.Type, err),
+				Subject:  f.BlockDefRange("hook", hook.Type),
+			})
+		}
+	}
+
+	return diags
+}
+
+// validateEnvironment checks that all environment variables have valid names and values,
+// and that they can be evaluated in context of file
+func (f *File) validateEnvironment(env *Environment) hcl.Diagnostics {
+	if env == nil {
+		return nil
+	}
+
+	attrs, diags := env.Config.JustAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	for _, attr := range attrs {
+		if err := validateEnvAttribute(attr); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid environment variable",
+				Detail:   fmt.Sprintf("Environment variable %q is invalid: %s.", attr.Name, err),
+				Subject:  &attr.NameRange,
+			})
+			continue
+		}
+
+		_, valueDiags := attr.Expr.Value(f.context)
+		diags = append(diags, valueDiags...)
+	}
+
+	return diags
+}
+
+// validateInputs checks that inputs only contain attributes, and that all variables used
+// in inputs reference a declared dependency, data source or variable in context.
+func (f *File) validateInputs(config *Config) hcl.Diagnostics {
+	if config.Inputs == nil {
+		return nil
+	}
+
+	_, diags := config.Inputs.Config.JustAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	trav, err := config.Inputs.ResolveVariables(config.Inputs.Config)
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid inputs block",
+			Detail:   err.Error(),
+			Subject:  f.BlockDefRange("inputs"),
+		})
+	}
+
+	deps := map[string]bool{}
+	for _, dep := range config.Dependencies {
+		deps[dep.Name] = true
+	}
+
+	datas := map[string]bool{}
+	for _, data := range config.Datas {
+		datas[fmt.Sprintf("%s.%s", data.Type, data.Name)] = true
+	}
+
+	for _, t := range trav {
+		subject := t.SourceRange()
+
+		switch t.RootName() {
+		case "dependency":
+			name, nameOk := traversalAttrName(t, 1)
+			outputs, _ := traversalAttrName(t, 2)
+
+			if !nameOk || outputs != "outputs" {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid dependency reference",
+					Detail:   "A reference to a dependency output must have the form dependency.NAME.outputs.OUTPUT.",
+					Subject:  &subject,
+				})
+				continue
+			}
+
+			if !deps[name] {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Reference to undeclared dependency",
+					Detail:   fmt.Sprintf("A dependency named %q has not been declared.", name),
+					Subject:  &subject,
+				})
+			}
+		case "data":
+			dataType, typeOk := traversalAttrName(t, 1)
+			dataName, nameOk := traversalAttrName(t, 2)
+
+			if !typeOk || !nameOk {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid data source reference",
+					Detail:   "A reference to a data source must have the form data.TYPE.NAME.",
+					Subject:  &subject,
+				})
+				continue
+			}
+
+			if !datas[fmt.Sprintf("%s.%s", dataType, dataName)] {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Reference to undeclared data source",
+					Detail:   fmt.Sprintf("A data source %s.%s has not been declared.", dataType, dataName),
+					Subject:  &subject,
+				})
+			}
+		case "local":
+			name, nameOk := traversalAttrName(t, 1)
+			locals := f.context.Variables["local"]
+
+			if nameOk && !locals.Type().HasAttribute(name) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Reference to undeclared local value",
+					Detail:   fmt.Sprintf("A local value named %q has not been declared.", name),
+					Subject:  &subject,
+				})
+			}
+		default:
+			if _, ok := f.context.Variables[t.RootName()]; !ok {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unknown variable",
+					Detail:   fmt.Sprintf("There is no variable named %q.", t.RootName()),
+					Subject:  &subject,
+				})
+			}
+		}
+	}
+
+	return diags
+}
+
+// validateDataBody evaluates all attributes in body, and nested blocks, without any
+// variables. That is how they are evaluated when generating data sources.
+func validateDataBody(body *hclsyntax.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	context := hclcontext.NewContext()
+
+	for _, attr := range body.Attributes {
+		_, valueDiags := attr.Expr.Value(context)
+		diags = append(diags, valueDiags...)
+	}
+
+	for _, block := range body.Blocks {
+		diags = append(diags, validateDataBody(block.Body)...)
+	}
+
+	return diags
+}
+
+// traversalAttrName returns the attribute name at index idx of traversal. Returns false
+// if traversal is too short or step is not an attribute
+func traversalAttrName(t hcl.Traversal, idx int) (string, bool) {
+	if len(t) <= idx {
+		return "", false
+	}
+
+	attr, ok := t[idx].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+
+	return attr.Name, true
+}
+
+// labelsMatch returns true if all labels in expected equal the labels at same position in actual
+func labelsMatch(actual, expected []string) bool {
+	if len(expected) > len(actual) {
+		return false
+	}
+
+	for idx, label := range expected {
+		if actual[idx] != label {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	validateTest1 = `
+		dependency "vnet" {
+			source = "./vnet.hcl"
+		}
+
+		data "azurerm_client_config" "current" {}
+
+		module {
+			source = "avinor/storage-account/azurerm"
+		}
+
+		inputs {
+			name      = source.name
+			subnet_id = dependency.vnet.outputs.subnets.id
+			tenant_id = data.azurerm_client_config.current.tenant_id
+		}
+	`
+
+	validateTest2 = `
+		inputs {
+			name = "missing module"
+		}
+	`
+
+	validateTest3 = `
+		module {
+			source = "test"
+		}
+
+		inputs {
+			a = dependency.vnet.outputs.id
+			b = dependency.vnet.id
+			c = data.azurerm_client_config.current.tenant_id
+			d = unknown.value
+		}
+	`
+
+	validateTest4 = `
+		module {
+			source = "test"
+		}
+
+		hook "invalid" {
+			command    = "echo"
+			trigger_on = "never"
+		}
+
+		environment_variables {
+			INVALID-NAME = "value"
+		}
+	`
+
+	validateTest5 = `
+		module {
+			source = "test"
+		}
+
+		data "azurerm_client_config" "current" {
+			name = source.name
+		}
+	`
+
+	validateTest6 = `
+		module {
+			source = "test"
+
+	`
+
+	validateTest7 = `
+		locals {
+			name = "${source.name}-rg"
+		}
+
+		module {
+			source = "test"
+		}
+
+		inputs {
+			name    = local.name
+			missing = local.missing
+		}
+	`
+)
+
+// TestFileValidate tests that all problems in a file are reported, and not just the first
+func TestFileValidate(t *testing.T) {
+	tests := []struct {
+		Content   string
+		Summaries []string
+	}{
+		{validateTest1, []string{}},
+		{validateTest2, []string{"Missing module block"}},
+		{validateTest3, []string{
+			"Reference to undeclared dependency",
+			"Invalid dependency reference",
+			"Reference to undeclared data source",
+			"Unknown variable",
+		}},
+		{validateTest4, []string{"Invalid hook block", "Invalid environment variable"}},
+		{validateTest5, []string{"Unknown variable"}},
+		{validateTest6, []string{"Argument or block definition required"}},
+		{validateTest7, []string{"Reference to undeclared local value"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			file, err := NewFile(fmt.Sprintf("/validate%d.hcl", i), []byte(test.Content))
+			assert.NoError(t, err)
+
+			summaries := []string{}
+			for _, diag := range file.Validate() {
+				summaries = append(summaries, diag.Summary)
+			}
+
+			assert.ElementsMatch(t, test.Summaries, summaries)
+		})
+	}
+}
+
+// TestBlockDefRange tests that the block range is found in file or children, and that
+// the range in file is preferred
+func TestBlockDefRange(t *testing.T) {
+	child, _ := NewFile("/blockrange_auto.hcl", []byte(validateTest4))
+	file, _ := NewFile("/blockrange.hcl", []byte(validateTest1))
+	file.AddChild(child)
+
+	tests := []struct {
+		Type     string
+		Labels   []string
+		Filename string
+		Line     int
+	}{
+		{"module", nil, "/blockrange.hcl", 8},
+		{"hook", []string{"invalid"}, "/blockrange_auto.hcl", 6},
+		{"data", []string{"azurerm_client_config"}, "/blockrange.hcl", 6},
+		{"data", []string{"azurerm_client_config", "other"}, "/blockrange.hcl", 1},
+		{"", nil, "/blockrange.hcl", 1},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			defRange := file.BlockDefRange(test.Type, test.Labels...)
+
+			assert.Equal(t, test.Filename, defRange.Filename)
+			assert.Equal(t, test.Line, defRange.Start.Line)
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"github.com/avinor/tau/pkg/config/comp"
+)
+
+const (
+	// VariableEnvPrefix is prefix of environment variables that set values of variables
+	VariableEnvPrefix = "TAU_VAR_"
+)
+
+var (
+	// variableArgInvalid is returned if a --var argument is not on the form NAME=VALUE
+	variableArgInvalid = errors.Errorf("variable must be on the form NAME=VALUE")
+
+	// variablesSchema is the schema to read only variable blocks from a file
+	variablesSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "variable", LabelNames: []string{"name"}},
+		},
+	}
+
+	// variableBlockSchema is the schema of a variable block
+	variableBlockSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "default"},
+			{Name: "description"},
+		},
+	}
+)
+
+// Variable declares an input variable to the tau file, that can be referenced as var.NAME in
+// all other blocks. Value is set from command line, a variable file or environment
+// variables. Variables without a default value have to be set.
+type Variable struct {
+	Name   string   `hcl:"name,label"`
+	Config hcl.Body `hcl:",remain"`
+
+	comp.Remainer
+}
+
+// mergeVariables merges the variable arrays into destination config. Variables have already
+// been evaluated when merging, so they are only kept for reference
+func mergeVariables(dest *Config, srcs []*Config) error {
+	for _, src := range srcs {
+		dest.Variables = append(dest.Variables, src.Variables...)
+	}
+
+	return nil
+}
+
+// SetVariableValues sets the values of variables declared in file. Values are used when
+// configuration is parsed, so it has to be called before Config or Validate
+func (f *File) SetVariableValues(values map[string]cty.Value) {
+	f.variableValues = values
+}
+
+// ParseVariableArg parses a variable argument on the form NAME=VALUE. Value is always a string
+func ParseVariableArg(arg string) (string, cty.Value, error) {
+	idx := strings.Index(arg, "=")
+	if idx < 1 {
+		return "", cty.NilVal, variableArgInvalid
+	}
+
+	return strings.TrimSpace(arg[:idx]), cty.StringVal(arg[idx+1:]), nil
+}
+
+// ParseVariableFile reads values of variables from filename. File contains attributes, one
+// for each variable, same as a terraform tfvars file
+func ParseVariableFile(filename string) (map[string]cty.Value, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	hclFile, diags := parser.ParseHCL(content, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	attrs, diags := hclFile.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	values := map[string]cty.Value{}
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// EnvVariableValues returns values of variables set with environment variables prefixed
+// TAU_VAR_. Environment is a list of KEY=VALUE, same as os.Environ returns
+func EnvVariableValues(environment []string) map[string]cty.Value {
+	values := map[string]cty.Value{}
+
+	for _, env := range environment {
+		if !strings.HasPrefix(env, VariableEnvPrefix) {
+			continue
+		}
+
+		name, value, err := ParseVariableArg(strings.TrimPrefix(env, VariableEnvPrefix))
+		if err != nil {
+			continue
+		}
+
+		values[name] = value
+	}
+
+	return values
+}
+
+// evaluateVariables evaluates variables declared in file and all children, and adds them to
+// evaluation context as var.NAME. Value is taken from values set on file, or the default
+// value. Values that are strings are converted to type of default value, see
+// convertVariableValue. It also checks that
+// all references to variables in file and children are to declared variables.
+func (f *File) evaluateVariables() hcl.Diagnostics {
+	blocks, diags := f.variableBlocks()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	names := []string{}
+	for name := range blocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := map[string]cty.Value{}
+
+	for _, name := range names {
+		block := blocks[name]
+
+		content, contentDiags := block.Body.Content(variableBlockSchema)
+		diags = append(diags, contentDiags...)
+
+		if contentDiags.HasErrors() {
+			continue
+		}
+
+		defaultValue := cty.NilVal
+		if attr, ok := content.Attributes["default"]; ok {
+			value, valueDiags := attr.Expr.Value(f.context)
+			diags = append(diags, valueDiags...)
+
+			if valueDiags.HasErrors() {
+				continue
+			}
+
+			defaultValue = value
+		}
+
+		value, ok := f.variableValues[name]
+		if !ok {
+			if defaultValue == cty.NilVal {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "No value for required variable",
+					Detail: fmt.Sprintf("Variable %q has no default value, set it with --var, --var-file or %s%s.",
+						name, VariableEnvPrefix, name),
+					Subject: block.DefRange.Ptr(),
+				})
+				continue
+			}
+
+			values[name] = defaultValue
+			continue
+		}
+
+		if defaultValue != cty.NilVal && value.Type() == cty.String {
+			converted, err := convertVariableValue(value, defaultValue.Type())
+			if err != nil {
+				detail := fmt.Sprintf("Value for variable %q must be a %s: %s.", name, defaultValue.Type().FriendlyName(), err)
+				if !defaultValue.Type().IsPrimitiveType() {
+					detail = fmt.Sprintf("Value for variable %q must be an expression of type %s, like "+
+						"[\"a\", \"b\"] or { key = \"value\" }: %s.", name, defaultValue.Type().FriendlyName(), err)
+				}
+
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid value for variable",
+					Detail:   detail,
+					Subject:  block.DefRange.Ptr(),
+				})
+				continue
+			}
+
+			value = converted
+		}
+
+		values[name] = value
+	}
+
+	f.context.Variables["var"] = cty.ObjectVal(values)
+
+	return append(diags, f.checkVariableReferences(blocks)...)
+}
+
+// convertVariableValue converts value set as a string, for instance with --var or an
+// environment variable, to type ty of default value. If default value is a list, map or
+// object the string is parsed as an expression, same as terraform does for -var, and must
+// be of same kind. Values of variables with a null default are not converted.
+func convertVariableValue(value cty.Value, ty cty.Type) (cty.Value, error) {
+	if ty == cty.DynamicPseudoType {
+		return value, nil
+	}
+
+	if ty.IsPrimitiveType() {
+		return convert.Convert(value, ty)
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(value.AsString()), "<value>", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	parsed, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	if isSequenceType(ty) != isSequenceType(parsed.Type()) || isMappingType(ty) != isMappingType(parsed.Type()) {
+		return cty.NilVal, errors.Errorf("value is a %s", parsed.Type().FriendlyName())
+	}
+
+	return parsed, nil
+}
+
+// isSequenceType returns true if ty is a list, set or tuple
+func isSequenceType(ty cty.Type) bool {
+	return ty.IsListType() || ty.IsSetType() || ty.IsTupleType()
+}
+
+// isMappingType returns true if ty is a map or object
+func isMappingType(ty cty.Type) bool {
+	return ty.IsMapType() || ty.IsObjectType()
+}
+
+// variableBlocks returns all variable blocks in file and children by name. Variables in file
+// override variables with same name in children
+func (f *File) variableBlocks() (map[string]*hcl.Block, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	blocks := map[string]*hcl.Block{}
+
+	for _, file := range append(f.children, f) {
+		hclFile, fileDiags := parser.ParseHCL(file.Content, file.FullPath)
+		diags = append(diags, fileDiags...)
+
+		if fileDiags.HasErrors() {
+			continue
+		}
+
+		content, _, contentDiags := hclFile.Body.PartialContent(variablesSchema)
+		diags = append(diags, contentDiags...)
+
+		for _, block := range content.Blocks {
+			blocks[block.Labels[0]] = block
+		}
+	}
+
+	return blocks, diags
+}
+
+// checkVariableReferences checks that all references to var.NAME in file and children are
+// to declared variables
+func (f *File) checkVariableReferences(blocks map[string]*hcl.Block) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, file := range append(f.children, f) {
+		hclFile, fileDiags := parser.ParseHCL(file.Content, file.FullPath)
+		if fileDiags.HasErrors() {
+			continue
+		}
+
+		body, ok := hclFile.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+			if !ok || expr.Traversal.RootName() != "var" {
+				return nil
+			}
+
+			name, ok := traversalAttrName(expr.Traversal, 1)
+			if !ok {
+				return nil
+			}
+
+			if _, declared := blocks[name]; !declared {
+				subject := expr.Traversal.SourceRange()
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Reference to undeclared input variable",
+					Detail:   fmt.Sprintf("An input variable named %q has not been declared, add a variable block to declare it.", name),
+					Subject:  &subject,
+				})
+			}
+
+			return nil
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	variablesTest1 = `
+		variable "region" {
+			default = "westeurope"
+		}
+
+		variable "count" {
+			default = 2
+		}
+
+		module {
+			source = "./"
+		}
+
+		inputs {
+			location = var.region
+			count    = var.count
+		}
+	`
+
+	variablesTest2 = `
+		variable "env" {
+			description = "Environment to deploy to"
+		}
+
+		inputs {
+			name = "tau-${var.env}"
+		}
+	`
+
+	variablesTest3 = `
+		inputs {
+			location = var.missing
+		}
+	`
+
+	variablesTest4 = `
+		variable "region" {
+			default = "norwayeast"
+		}
+
+		variable "enabled" {
+			default = true
+		}
+	`
+
+	variablesTest5 = `
+		variable "zones" {
+			default = ["1"]
+		}
+
+		variable "tags" {
+			default = {
+				env = "dev"
+			}
+		}
+	`
+)
+
+func TestParseVariableArg(t *testing.T) {
+	tests := []struct {
+		Arg   string
+		Name  string
+		Value string
+		Error error
+	}{
+		{"region=westeurope", "region", "westeurope", nil},
+		{"name=a=b", "name", "a=b", nil},
+		{"empty=", "empty", "", nil},
+		{"region", "", "", variableArgInvalid},
+		{"=value", "", "", variableArgInvalid},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			name, value, err := ParseVariableArg(test.Arg)
+
+			assert.Equal(t, test.Error, err)
+
+			if test.Error == nil {
+				assert.Equal(t, test.Name, name)
+				assert.Equal(t, test.Value, value.AsString())
+			}
+		})
+	}
+}
+
+func TestEnvVariableValues(t *testing.T) {
+	values := EnvVariableValues([]string{
+		"HOME=/root",
+		"TAU_VAR_region=westeurope",
+		"TAU_VAR_env=dev=1",
+		"TAU_VAR_=invalid",
+	})
+
+	assert.Equal(t, map[string]cty.Value{
+		"region": cty.StringVal("westeurope"),
+		"env":    cty.StringVal("dev=1"),
+	}, values)
+}
+
+func TestEvaluateVariables(t *testing.T) {
+	tests := []struct {
+		Content  string
+		Children []string
+		Values   map[string]cty.Value
+		Expected map[string]cty.Value
+		Error    string
+	}{
+		{
+			variablesTest1,
+			nil,
+			nil,
+			map[string]cty.Value{
+				"region": cty.StringVal("westeurope"),
+				"count":  cty.NumberIntVal(2),
+			},
+			"",
+		},
+		{
+			variablesTest1,
+			nil,
+			map[string]cty.Value{
+				"region": cty.StringVal("northeurope"),
+				"count":  cty.StringVal("5"),
+			},
+			map[string]cty.Value{
+				"region": cty.StringVal("northeurope"),
+				"count":  cty.NumberIntVal(5),
+			},
+			"",
+		},
+		{
+			variablesTest1,
+			nil,
+			map[string]cty.Value{
+				"count": cty.StringVal("five"),
+			},
+			nil,
+			"Invalid value for variable",
+		},
+		{
+			variablesTest2,
+			nil,
+			nil,
+			nil,
+			"No value for required variable",
+		},
+		{
+			variablesTest2,
+			nil,
+			map[string]cty.Value{
+				"env": cty.StringVal("dev"),
+			},
+			map[string]cty.Value{
+				"env": cty.StringVal("dev"),
+			},
+			"",
+		},
+		{
+			variablesTest3,
+			nil,
+			nil,
+			nil,
+			"Reference to undeclared input variable",
+		},
+		{
+			variablesTest1,
+			[]string{variablesTest4},
+			map[string]cty.Value{
+				"enabled": cty.StringVal("false"),
+			},
+			map[string]cty.Value{
+				"region":  cty.StringVal("westeurope"),
+				"count":   cty.NumberIntVal(2),
+				"enabled": cty.False,
+			},
+			"",
+		},
+		{
+			variablesTest5,
+			nil,
+			map[string]cty.Value{
+				"zones": cty.StringVal(`["1", "2"]`),
+				"tags":  cty.StringVal(`{ env = "prod", owner = "tau" }`),
+			},
+			map[string]cty.Value{
+				"zones": cty.TupleVal([]cty.Value{cty.StringVal("1"), cty.StringVal("2")}),
+				"tags":  cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod"), "owner": cty.StringVal("tau")}),
+			},
+			"",
+		},
+		{
+			variablesTest5,
+			nil,
+			map[string]cty.Value{
+				"zones": cty.StringVal("1,2"),
+			},
+			nil,
+			`Value for variable "zones" must be an expression of type tuple`,
+		},
+		{
+			variablesTest5,
+			nil,
+			map[string]cty.Value{
+				"tags": cty.StringVal(`["prod"]`),
+			},
+			nil,
+			`Value for variable "tags" must be an expression of type object`,
+		},
+		{
+			variablesTest5,
+			nil,
+			map[string]cty.Value{
+				"tags": cty.StringVal(`{ env = `),
+			},
+			nil,
+			"Invalid value for variable",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			file, _ := NewFile(fmt.Sprintf("/variables%02d/vnet.hcl", i), []byte(test.Content))
+			for idx, child := range test.Children {
+				childFile, _ := NewFile(fmt.Sprintf("/variables%02d/child%v_auto.hcl", i, idx), []byte(child))
+				file.AddChild(childFile)
+			}
+
+			file.SetVariableValues(test.Values)
+			diags := file.evaluateVariables()
+
+			if test.Error != "" {
+				assert.Contains(t, diags.Error(), test.Error)
+				return
+			}
+
+			assert.False(t, diags.HasErrors(), diags.Error())
+
+			vars := file.EvalContext().Variables["var"]
+			assert.Equal(t, len(test.Expected), len(vars.AsValueMap()))
+
+			for name, expected := range test.Expected {
+				assert.True(t, expected.RawEquals(vars.GetAttr(name)), name)
+			}
+		})
+	}
+}
+
+func TestVariablesInConfig(t *testing.T) {
+	file, _ := NewFile("/variables/vnet.hcl", []byte(variablesTest1))
+	file.SetVariableValues(map[string]cty.Value{"region": cty.StringVal("northeurope")})
+
+	config, err := file.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, len(config.Variables))
+
+	inputs, diags := config.Inputs.Config.JustAttributes()
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	value, diags := inputs["location"].Expr.Value(file.EvalContext())
+	assert.False(t, diags.HasErrors())
+	assert.Equal(t, "northeurope", value.AsString())
+}
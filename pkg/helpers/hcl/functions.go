@@ -1,7 +1,9 @@
 package hcl
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
@@ -23,3 +25,37 @@ var EnvFunc = function.New(&function.Spec{
 		return cty.StringVal(out), nil
 	},
 })
+
+// FindInParentFoldersFunc returns a function that searches for a file with given name in the
+// parent folders of dir, starting with the parent of dir and moving up. It returns the
+// absolute path of first file found, and fails if the file is not found in any parent folder
+func FindInParentFoldersFunc(dir string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{
+				Name: "name",
+				Type: cty.String,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			name := args[0].AsString()
+
+			current, err := filepath.Abs(dir)
+			if err != nil {
+				return cty.NilVal, err
+			}
+
+			for parent := filepath.Dir(current); parent != current; parent = filepath.Dir(current) {
+				current = parent
+				path := filepath.Join(current, name)
+
+				if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+					return cty.StringVal(path), nil
+				}
+			}
+
+			return cty.NilVal, fmt.Errorf("could not find %s in any parent folder of %s", name, dir)
+		},
+	})
+}
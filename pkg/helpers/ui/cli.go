@@ -8,19 +8,28 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 
 	"github.com/bgentry/speakeasy"
 	"github.com/fatih/color"
 	"github.com/mattn/go-isatty"
 )
 
-// CliHandler is the default handler and will write to stdout / stderr
+// CliHandler is the default handler and will write to stdout / stderr. It is safe
+// to use from several go routines, each line is written in one piece.
 type CliHandler struct {
 	Reader       io.Reader
 	OutputWriter io.Writer
 	LogWriter    io.Writer
 
 	previousLine string
+
+	// reader is created on first read from Reader, and reused so input buffered in
+	// a previous read is not lost when asking several times
+	reader *bufio.Reader
+
+	// lock makes sure lines from different go routines are not mixed together
+	lock sync.Mutex
 }
 
 // Ask user to input
@@ -92,6 +101,9 @@ func (hnd *CliHandler) NewLine() {
 // printLine writes a line to writer and saves it in temporary variable. It will
 // never print 2 empty lines after another.
 func (hnd *CliHandler) printLine(writer io.Writer, msg string, args ...interface{}) {
+	hnd.lock.Lock()
+	defer hnd.lock.Unlock()
+
 	if hnd.previousLine == "" && msg == "" {
 		return
 	}
@@ -125,8 +137,10 @@ func (hnd *CliHandler) ask(query string, secret bool) (string, error) {
 		if secret && isatty.IsTerminal(os.Stdin.Fd()) {
 			line, err = speakeasy.Ask("")
 		} else {
-			r := bufio.NewReader(hnd.Reader)
-			line, err = r.ReadString('\n')
+			if hnd.reader == nil {
+				hnd.reader = bufio.NewReader(hnd.Reader)
+			}
+			line, err = hnd.reader.ReadString('\n')
 		}
 		if err != nil {
 			errCh <- err
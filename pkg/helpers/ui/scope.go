@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Scope prints messages on behalf of a single unit of work, for instance one module when
+// several modules are processed in parallel. Every line is prefixed with the scope name so
+// it is possible to see where a line belongs even if output is mixed together.
+//
+// A nil scope, or a scope without prefix, prints exactly the same as the package level
+// functions.
+type Scope struct {
+	prefix string
+}
+
+// NewScope returns a new scope that prefixes all lines with prefix
+func NewScope(prefix string) *Scope {
+	return &Scope{
+		prefix: prefix,
+	}
+}
+
+// Debug prints a debug message, if debugging is activated
+func (s *Scope) Debug(msg string, args ...interface{}) {
+	Debug(s.format(msg), args...)
+}
+
+// Info prints an information message
+func (s *Scope) Info(msg string, args ...interface{}) {
+	Info(s.format(msg), args...)
+}
+
+// Warn prints a warning
+func (s *Scope) Warn(msg string, args ...interface{}) {
+	Warn(s.format(msg), args...)
+}
+
+// Error prints an error message
+func (s *Scope) Error(msg string, args ...interface{}) {
+	Error(s.format(msg), args...)
+}
+
+// Header prints a header. When prefixed it is printed as a bold info line
+func (s *Scope) Header(msg string) {
+	if !s.isPrefixed() {
+		Header(msg)
+		return
+	}
+
+	Info(s.format(color.New(color.Bold).Sprint(msg)))
+}
+
+// Separator between elements. When prefixed it is only printed if there is a title,
+// as a header line
+func (s *Scope) Separator(title string) {
+	if !s.isPrefixed() {
+		Separator(title)
+		return
+	}
+
+	if title != "" {
+		s.Header(title)
+	}
+}
+
+// NewLine adds a new line. When prefixed new lines are skipped, they do not make
+// much sense when output from several scopes are mixed together
+func (s *Scope) NewLine() {
+	if !s.isPrefixed() {
+		NewLine()
+	}
+}
+
+// isPrefixed returns true if lines should be prefixed
+func (s *Scope) isPrefixed() bool {
+	return s != nil && s.prefix != ""
+}
+
+// format adds the prefix to msg. Prefix is escaped so it is not interpreted as part
+// of the format string
+func (s *Scope) format(msg string) string {
+	if !s.isPrefixed() {
+		return msg
+	}
+
+	prefix := strings.ReplaceAll(s.prefix, "%", "%%")
+
+	return fmt.Sprintf("%s %s", color.CyanString("[%s]", prefix), msg)
+}
@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeFormat(t *testing.T) {
+	color.NoColor = true
+
+	tests := []struct {
+		Scope   *Scope
+		Message string
+		Args    []interface{}
+		Expects string
+	}{
+		{nil, "message %s", []interface{}{"a"}, "message a"},
+		{NewScope(""), "message %s", []interface{}{"a"}, "message a"},
+		{NewScope("file.hcl"), "message %s", []interface{}{"a"}, "[file.hcl] message a"},
+		{NewScope("100%.hcl"), "message", nil, "[100%.hcl] message"},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			actual := fmt.Sprintf(test.Scope.format(test.Message), test.Args...)
+
+			assert.Equal(t, test.Expects, actual)
+		})
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/avinor/tau/pkg/config"
 	"github.com/avinor/tau/pkg/config/loader"
 	pstrings "github.com/avinor/tau/pkg/helpers/strings"
-	"github.com/avinor/tau/pkg/helpers/ui"
 	"github.com/avinor/tau/pkg/hooks/command"
 	"github.com/avinor/tau/pkg/hooks/def"
 	"github.com/avinor/tau/pkg/hooks/script"
@@ -30,14 +29,19 @@ type Runner struct {
 	// cache of all created executors
 	cache map[string]def.Executor
 
+	// executorLocks has a lock for each cached executor, so same cached hook is only run
+	// once when files are processed in parallel. Different hooks can run at the same time
+	executorLocks map[string]*sync.Mutex
+
 	creators []def.ExecutorCreator
 }
 
 // New creates a new runner for executing hooks.
 func New(options *def.Options) *Runner {
 	return &Runner{
-		options: options,
-		cache:   map[string]def.Executor{},
+		options:       options,
+		cache:         map[string]def.Executor{},
+		executorLocks: map[string]*sync.Mutex{},
 		creators: []def.ExecutorCreator{
 			&command.Creator{},
 			&script.Creator{
@@ -51,31 +55,28 @@ func New(options *def.Options) *Runner {
 // got specific terraform commands.
 func (r *Runner) Run(file *loader.ParsedFile, event, command string) error {
 	for _, hook := range file.Config.Hooks {
-		exec, err := r.getExecutor(hook)
+		exec, lock, err := r.getExecutor(hook)
 		if err != nil {
 			return err
 		}
 
 		if !r.ShouldRun(hook, event, command) {
-			ui.Debug("%s should not run for command %s", hook.Type, command)
+			file.Log.Debug("%s should not run for command %s", hook.Type, command)
 			continue
 		}
 
-		if !exec.HasRun() || (hook.DisableCache != nil && *hook.DisableCache) {
-			ui.Info("- Running hook %s...", hook.Type)
-
-			if err := exec.Run(file.Env); err != nil {
-				if hook.FailOnError != nil && !*hook.FailOnError {
-					continue
-				}
-
-				return err
+		output, err := r.runExecutor(file, hook, exec, lock)
+		if err != nil {
+			if hook.FailOnError != nil && !*hook.FailOnError {
+				continue
 			}
+
+			return err
 		}
 
 		if hook.SetEnv != nil && *hook.SetEnv {
-			for key, value := range pstrings.ParseVars(exec.Output()) {
-				ui.Debug("setting env %s", key)
+			for key, value := range pstrings.ParseVars(output) {
+				file.Log.Debug("setting env %s", key)
 				file.Env[key] = value
 			}
 		}
@@ -84,6 +85,24 @@ func (r *Runner) Run(file *loader.ParsedFile, event, command string) error {
 	return nil
 }
 
+// runExecutor runs executor for hook, unless it has already run and output is cached, and
+// returns the output. Lock is held while running, so other files using same executor wait
+// for it to complete and then use the cached output
+func (r *Runner) runExecutor(file *loader.ParsedFile, hook *config.Hook, exec def.Executor, lock *sync.Mutex) (string, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !exec.HasRun() || (hook.DisableCache != nil && *hook.DisableCache) {
+		file.Log.Info("- Running hook %s...", hook.Type)
+
+		if err := exec.Run(file.Env); err != nil {
+			return "", err
+		}
+	}
+
+	return exec.Output(), nil
+}
+
 // ShouldRun checks if the hook should run for event and command sent as input.
 // Returns true if it should continue to process hook, and false otherwise.
 func (r *Runner) ShouldRun(hook *config.Hook, event, command string) bool {
@@ -119,28 +138,30 @@ func (r *Runner) ShouldRun(hook *config.Hook, event, command string) bool {
 
 // getExecutor checks if executor has already been created and returns from cache if it has.
 // If not it will create a new executor using the creators and store in cache for later use.
-func (r *Runner) getExecutor(hook *config.Hook) (def.Executor, error) {
+// Returned lock has to be held while running the executor.
+func (r *Runner) getExecutor(hook *config.Hook) (def.Executor, *sync.Mutex, error) {
 	key := getCacheKey(hook)
 	r.cacheLock.Lock()
 	defer r.cacheLock.Unlock()
 
 	if _, exists := r.cache[key]; exists {
-		return r.cache[key], nil
+		return r.cache[key], r.executorLocks[key], nil
 	}
 
 	for _, creator := range r.creators {
 		if creator.CanCreate(hook) {
 			executor, err := creator.Create(hook)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			r.cache[key] = executor
-			return r.cache[key], nil
+			r.executorLocks[key] = &sync.Mutex{}
+			return r.cache[key], r.executorLocks[key], nil
 		}
 	}
 
-	return nil, noExecutorFound
+	return nil, nil, noExecutorFound
 }
 
 // getCacheKey returns a unique cache key for a given command with arguments. If disable_cache
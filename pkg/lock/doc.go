@@ -0,0 +1,6 @@
+// Package lock implements advisory locks using lock files. A lock file records who holds
+// the lock, so a second process trying to take it can report who is using it.
+//
+// Locks are advisory, they only protect against processes that take the same lock. A
+// lock left behind by a process that did not exit cleanly has to be removed manually.
+package lock
@@ -0,0 +1,168 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// retryInterval is time to wait between each attempt to take a lock
+	retryInterval = time.Second
+
+	// lockNotHeld is returned when releasing a lock that is not held
+	lockNotHeld = errors.Errorf("lock is not held")
+)
+
+// Info is the information about who holds a lock, it is written to lock file
+type Info struct {
+	PID     int       `json:"pid"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Created time.Time `json:"created"`
+}
+
+// Lock is a lock held by current process
+type Lock struct {
+	Path string
+	Info *Info
+}
+
+// LockedError is returned when lock is held by someone else
+type LockedError struct {
+	Path string
+	Info *Info
+}
+
+// Error implements the error interface
+func (e *LockedError) Error() string {
+	if e.Info == nil {
+		return fmt.Sprintf("locked by unknown process (%s)", e.Path)
+	}
+
+	return fmt.Sprintf("locked by %s@%s (pid %v) running '%s' since %s",
+		e.Info.User, e.Info.Host, e.Info.PID, e.Info.Command, e.Info.Created.Local().Format(time.RFC3339))
+}
+
+// NewInfo returns information about current process running command
+func NewInfo(command string) *Info {
+	username := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return &Info{
+		PID:     os.Getpid(),
+		User:    username,
+		Host:    host,
+		Command: command,
+		Created: time.Now().UTC(),
+	}
+}
+
+// Acquire takes the lock at path. If lock is held by someone else it will retry until
+// timeout has passed, and then return a LockedError. With a timeout of zero it only
+// tries once.
+func Acquire(path string, info *Info, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := create(path, info)
+		if err == nil {
+			return &Lock{Path: path, Info: info}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if !time.Now().Before(deadline) {
+			held, _ := Read(path)
+			return nil, &LockedError{Path: path, Info: held}
+		}
+
+		time.Sleep(retryInterval)
+	}
+}
+
+// Release removes the lock file. It does not remove the lock if someone else has taken it
+// after lock was removed manually.
+func (l *Lock) Release() error {
+	held, err := Read(l.Path)
+	if os.IsNotExist(err) {
+		return lockNotHeld
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if held.PID != l.Info.PID || held.Host != l.Info.Host || !held.Created.Equal(l.Info.Created) {
+		return lockNotHeld
+	}
+
+	return os.Remove(l.Path)
+}
+
+// Read returns the information in lock file at path
+func Read(path string) (*Info, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{}
+	if err := json.Unmarshal(content, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Remove removes the lock at path, no matter who holds it. Should only be used to remove
+// stale locks.
+func Remove(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// create creates lock file at path, failing if it already exists
+func create(path string, info *Info) error {
+	content, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return f.Close()
+}
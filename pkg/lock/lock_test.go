@@ -0,0 +1,104 @@
+package lock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		Held     *Info
+		Timeout  time.Duration
+		Expected error
+	}{
+		{nil, 0, nil},
+		{
+			&Info{PID: 10, User: "user", Host: "host", Command: "tau apply", Created: time.Unix(0, 0)},
+			0,
+			&LockedError{Info: &Info{PID: 10, User: "user", Host: "host", Command: "tau apply", Created: time.Unix(0, 0)}},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			path := tempLockFile(t)
+
+			if test.Held != nil {
+				assert.NoError(t, create(path, test.Held))
+			}
+
+			lock, err := Acquire(path, NewInfo("tau plan"), test.Timeout)
+
+			if test.Expected != nil {
+				assert.Nil(t, lock)
+				assert.IsType(t, test.Expected, err)
+				assert.Equal(t, test.Held.PID, err.(*LockedError).Info.PID)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			info, err := Read(path)
+			assert.NoError(t, err)
+			assert.Equal(t, os.Getpid(), info.PID)
+			assert.Equal(t, "tau plan", info.Command)
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	tests := []struct {
+		Replace  bool
+		Remove   bool
+		Expected error
+	}{
+		{false, false, nil},
+		{true, false, lockNotHeld},
+		{false, true, lockNotHeld},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			path := tempLockFile(t)
+
+			lock, err := Acquire(path, NewInfo("tau apply"), 0)
+			assert.NoError(t, err)
+
+			if test.Replace || test.Remove {
+				assert.NoError(t, Remove(path))
+			}
+
+			if test.Replace {
+				assert.NoError(t, create(path, &Info{PID: 1, Host: "other"}))
+			}
+
+			err = lock.Release()
+
+			if test.Expected != nil {
+				assert.EqualError(t, err, test.Expected.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NoFileExists(t, path)
+		})
+	}
+}
+
+// tempLockFile returns path to a lock file in a new temporary directory
+func tempLockFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tau-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "test.lock")
+}
@@ -0,0 +1,4 @@
+// Package scaffold creates new tau files from a terraform module. It reads the variables
+// declared in module and generates a file with a module block and an inputs block, where
+// required variables have to be set and optional variables are commented out.
+package scaffold
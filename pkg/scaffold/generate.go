@@ -0,0 +1,155 @@
+package scaffold
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Options for generating a new tau file
+type Options struct {
+	// Source and Version of module, version is only set for terraform registry modules
+	Source  string
+	Version string
+
+	// Variables declared in module
+	Variables []*Variable
+
+	// BackendFile is name of the auto import file that defines backend. If empty a
+	// backend block has to be added to the new file.
+	BackendFile string
+}
+
+// Generate returns the content of a new tau file. Required variables are added to inputs
+// with an empty value of their type, optional variables are commented out with default.
+func Generate(options *Options) []byte {
+	var b strings.Builder
+
+	b.WriteString("module {\n")
+	fmt.Fprintf(&b, "source = %s\n", strconv.Quote(options.Source))
+	if options.Version != "" {
+		fmt.Fprintf(&b, "version = %s\n", strconv.Quote(options.Version))
+	}
+	b.WriteString("}\n\n")
+
+	if options.BackendFile != "" {
+		fmt.Fprintf(&b, "# backend is inherited from %s\n\n", options.BackendFile)
+	} else {
+		b.WriteString("# Define backend for remote state, or define it in an _auto file in same folder\n")
+		b.WriteString("# backend \"TYPE\" {\n# }\n\n")
+	}
+
+	required := []*Variable{}
+	optional := []*Variable{}
+
+	for _, variable := range options.Variables {
+		if variable.Required() {
+			required = append(required, variable)
+		} else {
+			optional = append(optional, variable)
+		}
+	}
+
+	b.WriteString("inputs {\n")
+
+	for i, variable := range required {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		writeComments(&b, variable)
+		fmt.Fprintf(&b, "%s = %s\n", variable.Name, emptyValue(variable.Type))
+	}
+
+	if len(optional) > 0 {
+		if len(required) > 0 {
+			b.WriteString("\n")
+		}
+
+		b.WriteString("# Optional variables\n")
+	}
+
+	for _, variable := range optional {
+		b.WriteString("\n")
+		writeComments(&b, variable)
+		writeCommented(&b, fmt.Sprintf("%s = %s", variable.Name, variable.Default))
+	}
+
+	b.WriteString("}\n")
+
+	return hclwrite.Format([]byte(b.String()))
+}
+
+// writeComments writes description and type of variable as comments
+func writeComments(b *strings.Builder, variable *Variable) {
+	if variable.Description != "" {
+		writeCommented(b, variable.Description)
+	}
+
+	if variable.Type != "" {
+		writeCommented(b, fmt.Sprintf("type: %s", variable.Type))
+	}
+}
+
+// writeCommented writes text with all lines commented out
+func writeCommented(b *strings.Builder, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		fmt.Fprintf(b, "# %s\n", strings.TrimRight(line, " \t"))
+	}
+}
+
+// emptyValue returns an empty value for type, or null if type is unknown
+func emptyValue(typ string) string {
+	typ = strings.ReplaceAll(typ, " ", "")
+
+	switch {
+	case typ == "string":
+		return `""`
+	case typ == "number":
+		return "0"
+	case typ == "bool":
+		return "false"
+	case strings.HasPrefix(typ, "list("), strings.HasPrefix(typ, "set("), strings.HasPrefix(typ, "tuple("):
+		return "[]"
+	case strings.HasPrefix(typ, "map("), strings.HasPrefix(typ, "object("):
+		return "{}"
+	}
+
+	return "null"
+}
+
+// ModuleName returns a name for module from its source, that can be used as file name.
+// Registry sources are namespace/name/provider, for other sources it is last part of path.
+func ModuleName(source, version string) string {
+	if version != "" {
+		if parts := strings.Split(source, "/"); len(parts) == 3 {
+			return parts[1]
+		}
+	}
+
+	if idx := strings.Index(source, "::"); idx >= 0 {
+		source = source[idx+2:]
+	}
+
+	if idx := strings.Index(source, "?"); idx >= 0 {
+		source = source[:idx]
+	}
+
+	source = strings.TrimRight(source, "/")
+
+	if idx := strings.LastIndex(source, "//"); idx >= 0 && !strings.HasSuffix(source[:idx], ":") {
+		source = source[idx+2:]
+	}
+
+	name := source[strings.LastIndexAny(source, "/:")+1:]
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	if name == "" || name == "." || name == ".." {
+		return "module"
+	}
+
+	return name
+}
@@ -0,0 +1,128 @@
+package scaffold
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	generateExpected1 = `module {
+  source  = "avinor/kubernetes/azurerm"
+  version = "1.0.0"
+}
+
+# backend is inherited from backend_auto.hcl
+
+inputs {
+  # Name of cluster
+  # type: string
+  name = ""
+
+  # type: list(string)
+  zones = []
+
+  # Optional variables
+
+  # Tags to add
+  # type: map(string)
+  # tags = {
+  #   env = "dev"
+  # }
+}
+`
+
+	generateExpected2 = `module {
+  source = "./module"
+}
+
+# Define backend for remote state, or define it in an _auto file in same folder
+# backend "TYPE" {
+# }
+
+inputs {
+  value = null
+}
+`
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		Options  *Options
+		Expected string
+	}{
+		{
+			&Options{
+				Source:  "avinor/kubernetes/azurerm",
+				Version: "1.0.0",
+				Variables: []*Variable{
+					{Name: "name", Type: "string", Description: "Name of cluster"},
+					{Name: "tags", Type: "map(string)", Default: "{\n  env = \"dev\"\n}", Description: "Tags to add"},
+					{Name: "zones", Type: "list(string)"},
+				},
+				BackendFile: "backend_auto.hcl",
+			},
+			generateExpected1,
+		},
+		{
+			&Options{
+				Source:    "./module",
+				Variables: []*Variable{{Name: "value"}},
+			},
+			generateExpected2,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, string(Generate(test.Options)))
+		})
+	}
+}
+
+func TestEmptyValue(t *testing.T) {
+	tests := []struct {
+		Type     string
+		Expected string
+	}{
+		{"string", `""`},
+		{"number", "0"},
+		{"bool", "false"},
+		{"list(string)", "[]"},
+		{"set(number)", "[]"},
+		{"map(any)", "{}"},
+		{"object({ name = string })", "{}"},
+		{"any", "null"},
+		{"", "null"},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, emptyValue(test.Type))
+		})
+	}
+}
+
+func TestModuleName(t *testing.T) {
+	tests := []struct {
+		Source   string
+		Version  string
+		Expected string
+	}{
+		{"avinor/kubernetes/azurerm", "1.0.0", "kubernetes"},
+		{"./modules/vnet", "", "vnet"},
+		{"./modules/vnet/", "", "vnet"},
+		{"../", "", "module"},
+		{"git::https://github.com/avinor/terraform-azurerm-vnet.git?ref=v1.0.0", "", "terraform-azurerm-vnet"},
+		{"github.com/avinor/modules//vnet?ref=v1.0.0", "", "vnet"},
+		{"git@github.com:avinor/vnet.git", "", "vnet"},
+		{"https://example.com/vnet.zip", "", "vnet"},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			assert.Equal(t, test.Expected, ModuleName(test.Source, test.Version))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package scaffold
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var (
+	// variableSchema is the schema for variable blocks in terraform files
+	variableSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "variable", LabelNames: []string{"name"}},
+		},
+	}
+
+	// variableBodySchema is the schema for attributes of a variable block
+	variableBodySchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "type"},
+			{Name: "default"},
+			{Name: "description"},
+			{Name: "sensitive"},
+		},
+	}
+)
+
+// Variable is a variable declared in a terraform module. Type and Default are the
+// expressions as written in source, Default is empty if variable has no default value.
+type Variable struct {
+	Name        string
+	Type        string
+	Default     string
+	Description string
+	Sensitive   bool
+}
+
+// Required returns true if variable has no default value
+func (v *Variable) Required() bool {
+	return v.Default == ""
+}
+
+// ParseVariables reads all terraform files in dir and returns the variables declared. They
+// are returned in the order they are declared, with files read in alphabetical order.
+func ParseVariables(dir string) ([]*Variable, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	parser := hclparse.NewParser()
+	variables := []*Variable{}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, diags := parser.ParseHCL(content, file)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		vars, diags := parseVariables(parsed.Body, content)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		variables = append(variables, vars...)
+	}
+
+	return variables, nil
+}
+
+// parseVariables returns the variables declared in body, src is the source of body
+func parseVariables(body hcl.Body, src []byte) ([]*Variable, hcl.Diagnostics) {
+	content, _, diags := body.PartialContent(variableSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	variables := []*Variable{}
+
+	for _, block := range content.Blocks {
+		attrs, _, diags := block.Body.PartialContent(variableBodySchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		variable := &Variable{Name: block.Labels[0]}
+
+		if attr, ok := attrs.Attributes["type"]; ok {
+			variable.Type = sourceText(attr.Expr, src)
+		}
+
+		if attr, ok := attrs.Attributes["default"]; ok {
+			variable.Default = sourceText(attr.Expr, src)
+		}
+
+		if attr, ok := attrs.Attributes["description"]; ok {
+			value, diags := attr.Expr.Value(nil)
+			if !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() && !value.IsNull() {
+				variable.Description = value.AsString()
+			} else {
+				variable.Description = sourceText(attr.Expr, src)
+			}
+		}
+
+		if attr, ok := attrs.Attributes["sensitive"]; ok {
+			value, diags := attr.Expr.Value(nil)
+			if !diags.HasErrors() && value.Type() == cty.Bool && value.IsKnown() && !value.IsNull() {
+				variable.Sensitive = value.True()
+			}
+		}
+
+		variables = append(variables, variable)
+	}
+
+	return variables, nil
+}
+
+// sourceText returns the source of expression
+func sourceText(expr hcl.Expression, src []byte) string {
+	return strings.TrimSpace(string(expr.Range().SliceBytes(src)))
+}
@@ -0,0 +1,82 @@
+package scaffold
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVariables(t *testing.T) {
+	tests := []struct {
+		Content  string
+		Expected []*Variable
+	}{
+		{
+			`variable "name" {}`,
+			[]*Variable{{Name: "name"}},
+		},
+		{
+			`
+			variable "name" {
+				description = "Name of resource"
+				type        = string
+			}
+
+			variable "tags" {
+				type    = map(string)
+				default = {}
+			}
+
+			variable "password" {
+				type      = string
+				sensitive = true
+			}
+
+			resource "null_resource" "test" {}
+			`,
+			[]*Variable{
+				{Name: "name", Type: "string", Description: "Name of resource"},
+				{Name: "tags", Type: "map(string)", Default: "{}"},
+				{Name: "password", Type: "string", Sensitive: true},
+			},
+		},
+		{
+			`
+			variable "subnets" {
+				type = list(object({
+					name = string
+				}))
+				default = [
+					{ name = "a" },
+				]
+
+				validation {
+					condition     = length(var.subnets) > 0
+					error_message = "At least one subnet."
+				}
+			}
+			`,
+			[]*Variable{
+				{
+					Name:    "subnets",
+					Type:    "list(object({\n\t\t\t\t\tname = string\n\t\t\t\t}))",
+					Default: "[\n\t\t\t\t\t{ name = \"a\" },\n\t\t\t\t]",
+				},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			file, diags := hclparse.NewParser().ParseHCL([]byte(test.Content), "variables.tf")
+			assert.False(t, diags.HasErrors(), diags.Error())
+
+			variables, diags := parseVariables(file.Body, []byte(test.Content))
+			assert.False(t, diags.HasErrors(), diags.Error())
+
+			assert.Equal(t, test.Expected, variables)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package shell
+
+import (
+	"fmt"
+)
+
+// ExitError is returned when a command exits with a non-zero exit code. Some commands
+// use exit code to return status, so callers can check ExitCode to decide if it failed.
+type ExitError struct {
+	Command  string
+	ExitCode int
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s command exited with exit code %v", e.Command, e.ExitCode)
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/go-cmd/cmd"
-	"github.com/go-errors/errors"
 
 	"github.com/avinor/tau/pkg/helpers/ui"
 )
@@ -29,14 +28,7 @@ func Execute(options *Options, command string, args ...string) error {
 		execCmd.Dir = options.WorkingDirectory
 	}
 
-	execCmd.Env = os.Environ()
-
-	if len(options.Env) > 0 {
-		for k, v := range options.Env {
-			variable := fmt.Sprintf("%s=%s", k, v)
-			execCmd.Env = append(execCmd.Env, variable)
-		}
-	}
+	execCmd.Env = environment(options)
 
 	ui.Debug("environment variables: %#v", execCmd.Env)
 	ui.Debug("command: %s %s", execCmd.Name, strings.Join(execCmd.Args, " "))
@@ -74,7 +66,7 @@ func Execute(options *Options, command string, args ...string) error {
 	}
 
 	if status.Exit != 0 {
-		return errors.Errorf("%s command exited with exit code %v", command, status.Exit)
+		return &ExitError{Command: command, ExitCode: status.Exit}
 	}
 
 	return nil
@@ -87,3 +79,15 @@ func processLine(processors []OutputProcessor, line string) {
 		}
 	}
 }
+
+// environment returns the environment variables for command, os environment with
+// the variables in options appended
+func environment(options *Options) []string {
+	env := os.Environ()
+
+	for k, v := range options.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	return env
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/avinor/tau/pkg/helpers/ui"
+)
+
+// Output executes a shell command and returns everything it writes to stdout. Unlike
+// Execute it does not stream stdout line by line, so it can be used for commands that
+// write very long lines, for instance json output. Stderr is sent to options.Stderr
+// processors when command has finished. Options.Stdout is ignored.
+func Output(options *Options, command string, args ...string) ([]byte, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	execCmd := exec.Command(command, args...)
+	execCmd.Dir = options.WorkingDirectory
+	execCmd.Env = environment(options)
+	execCmd.Stdout = &stdout
+	execCmd.Stderr = &stderr
+
+	ui.Debug("environment variables: %#v", execCmd.Env)
+	ui.Debug("command: %s %s", command, strings.Join(args, " "))
+
+	err := execCmd.Run()
+
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		processLine(options.Stderr, scanner.Text())
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, &ExitError{Command: command, ExitCode: exitErr.ExitCode()}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
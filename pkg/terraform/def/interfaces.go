@@ -11,8 +11,13 @@ import (
 // Each dependency processor will run in its own context, with separate environment variables.
 // All dependency resolving that can be done in same context can be run in one processor, but
 // use multiple processors to separate the context they run in
+//
+// Name and Content return the name of dependency and the terraform code used to resolve it,
+// without processing it.
 type DependencyProcessor interface {
 	Process() (map[string]cty.Value, bool, error)
+	Name() string
+	Content() []byte
 }
 
 // OutputProcessor can parse the output from terraform and parse it into a map of values.
@@ -42,4 +47,6 @@ type Generator interface {
 type Executor interface {
 	Execute(options *shell.Options, command string, args ...string) error
 	NewOutputProcessor() OutputProcessor
+	SummarizePlan(options *shell.Options, planFile string) (*PlanSummary, error)
+	SelectWorkspace(options *shell.Options, workspace string) error
 }
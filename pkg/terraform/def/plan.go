@@ -0,0 +1,31 @@
+package def
+
+// PlanSummary is the number of resources that will be changed by a terraform plan,
+// grouped by the type of change. Replaced resources are destroyed and created again,
+// they are not counted as added or destroyed.
+type PlanSummary struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Replace int `json:"replace"`
+	Destroy int `json:"destroy"`
+
+	Resources []*ResourceChange `json:"resources,omitempty"`
+}
+
+// ResourceChange is a single resource that will be changed by a plan. Action is one
+// of add, change, replace or destroy
+type ResourceChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// HasChanges returns true if plan will change any resources
+func (ps *PlanSummary) HasChanges() bool {
+	return ps.Add+ps.Change+ps.Replace+ps.Destroy > 0
+}
+
+// HasDestroy returns true if plan will destroy any resources, either by destroying
+// them or replacing them
+func (ps *PlanSummary) HasDestroy() bool {
+	return ps.Replace+ps.Destroy > 0
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/zclconf/go-cty/cty"
 
 	"github.com/avinor/tau/pkg/config/loader"
-	"github.com/avinor/tau/pkg/helpers/ui"
 	"github.com/avinor/tau/pkg/hooks"
 	"github.com/avinor/tau/pkg/shell"
 	"github.com/avinor/tau/pkg/shell/processors"
@@ -54,10 +53,20 @@ func NewDependencyProcessor(file *loader.ParsedFile, depFile *loader.ParsedFile,
 	}
 }
 
+// Name returns the name of dependency, it is used as directory name when processing
+func (d *DependencyProcessor) Name() string {
+	return d.DepFile.Name
+}
+
+// Content returns the content of main.tf that is used to resolve dependency
+func (d *DependencyProcessor) Content() []byte {
+	return d.File.Bytes()
+}
+
 // WriteContent writes the context of main.tf
 func (d *DependencyProcessor) WriteContent(dest string) error {
 	file := filepath.Join(dest, "main.tf")
-	if err := ioutil.WriteFile(file, d.File.Bytes(), os.ModePerm); err != nil {
+	if err := ioutil.WriteFile(file, d.Content(), os.ModePerm); err != nil {
 		return err
 	}
 
@@ -66,13 +75,15 @@ func (d *DependencyProcessor) WriteContent(dest string) error {
 
 // Process the dependency and return the variables from output.
 func (d *DependencyProcessor) Process() (map[string]cty.Value, bool, error) {
-	dest := d.ParsedFile.DependencyDir(d.DepFile.Name)
+	dest := d.ParsedFile.DependencyDir(d.Name())
 	if err := d.WriteContent(dest); err != nil {
 		return nil, false, err
 	}
 
-	debugLog := processors.NewUI(ui.Debug)
-	errorLog := processors.NewUI(ui.Error)
+	log := d.ParsedFile.Log
+
+	debugLog := processors.NewUI(log.Debug)
+	errorLog := processors.NewUI(log.Error)
 
 	options := &shell.Options{
 		Stdout:           shell.Processors(debugLog),
@@ -91,14 +102,14 @@ func (d *DependencyProcessor) Process() (map[string]cty.Value, bool, error) {
 
 	base := filepath.Base(dest)
 
-	ui.Info("- Processing dependency %s", base)
+	log.Info("- Processing dependency %s", base)
 
-	ui.Debug("running terraform init on %s", base)
+	log.Debug("running terraform init on %s", base)
 	if err := d.executor.Execute(options, "init", "-input=false"); err != nil {
 		return nil, false, err
 	}
 
-	ui.Debug("running terraform apply on %s", base)
+	log.Debug("running terraform apply on %s", base)
 	if err := d.executor.Execute(options, "apply", "-auto-approve", "-input=false"); err != nil {
 		// If it accepts failure then just exit with no error, but create = false
 		if d.acceptApplyFailure {
@@ -111,7 +122,7 @@ func (d *DependencyProcessor) Process() (map[string]cty.Value, bool, error) {
 	outputProcessor := &OutputProcessor{decodeNames: true}
 	options.Stdout = shell.Processors(outputProcessor)
 
-	ui.Debug("reading output from %s", base)
+	log.Debug("reading output from %s", base)
 	if err := d.executor.Execute(options, "output", "-json"); err != nil {
 		return nil, false, err
 	}
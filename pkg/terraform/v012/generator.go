@@ -1,6 +1,8 @@
 package v012
 
 import (
+	"sort"
+
 	"github.com/go-errors/errors"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
@@ -39,8 +41,8 @@ func (g *Generator) GenerateOverrides(file *loader.ParsedFile) ([]byte, bool, er
 		return nil, false, err
 	}
 
-	for k, v := range values {
-		backendBody.SetAttributeValue(k, v)
+	for _, k := range sortedKeys(values) {
+		backendBody.SetAttributeValue(k, values[k])
 	}
 
 	return f.Bytes(), true, nil
@@ -96,8 +98,8 @@ func (g *Generator) GenerateVariables(file *loader.ParsedFile) ([]byte, error) {
 		return nil, diags
 	}
 
-	for name, value := range values {
-		rootBody.SetAttributeValue(name, value)
+	for _, name := range sortedKeys(values) {
+		rootBody.SetAttributeValue(name, values[name])
 	}
 
 	return f.Bytes(), nil
@@ -130,7 +132,7 @@ func (g *Generator) generateHclWriterBlock(typeName string, labels []string, bod
 	return block, nil
 }
 
-func (g *Generator) generateRemoteBackendBlock(file *loader.ParsedFile, name string, backend *config.Backend) (*hclwrite.Block, error) {
+func (g *Generator) generateRemoteBackendBlock(file *loader.ParsedFile, name string, backend *config.Backend, workspace string) (*hclwrite.Block, error) {
 	block := hclwrite.NewBlock("data", []string{"terraform_remote_state", name})
 	blockBody := block.Body()
 
@@ -142,6 +144,10 @@ func (g *Generator) generateRemoteBackendBlock(file *loader.ParsedFile, name str
 	blockBody.SetAttributeValue("backend", cty.StringVal(backend.Type))
 	blockBody.SetAttributeValue("config", cty.MapVal(values))
 
+	if workspace != "" {
+		blockBody.SetAttributeValue("workspace", cty.StringVal(workspace))
+	}
+
 	return block, nil
 }
 
@@ -185,7 +191,13 @@ func (g *Generator) generateDepProcessor(file *loader.ParsedFile, dep *config.De
 		return nil, err
 	}
 
-	block, err := g.generateRemoteBackendBlock(depFile, dep.Name, backend)
+	// Read from workspace dependency is deployed to, unless dependency block overrides it
+	workspace := depFile.Config.Workspace
+	if dep.Workspace != "" {
+		workspace = dep.Workspace
+	}
+
+	block, err := g.generateRemoteBackendBlock(depFile, dep.Name, backend, workspace)
 	if err != nil {
 		return nil, err
 	}
@@ -244,9 +256,15 @@ func generateOutputBlocks(trav []hcl.Traversal, rootName, name string) []*hclwri
 		blocks[string(fullname)] = block
 	}
 
+	names := []string{}
+	for name := range blocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	ret := []*hclwrite.Block{}
-	for _, block := range blocks {
-		ret = append(ret, block)
+	for _, name := range names {
+		ret = append(ret, blocks[name])
 	}
 
 	return ret
@@ -299,3 +317,16 @@ func processBackendBody(body hcl.Body, context *hcl.EvalContext) (map[string]cty
 
 	return values, nil
 }
+
+// sortedKeys returns the keys of values sorted, so generated files are always written in
+// same order
+func sortedKeys(values map[string]cty.Value) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
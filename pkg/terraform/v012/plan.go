@@ -0,0 +1,73 @@
+package v012
+
+import (
+	"encoding/json"
+
+	"github.com/avinor/tau/pkg/shell"
+	"github.com/avinor/tau/pkg/terraform/def"
+)
+
+// plan is the subset of `terraform show -json` output that is needed to summarize
+// the changes in a plan
+type plan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// SummarizePlan runs `terraform show -json` on planFile and counts the resources changed
+func (e *Executor) SummarizePlan(options *shell.Options, planFile string) (*def.PlanSummary, error) {
+	out, err := shell.Output(options, "terraform", "show", "-json", planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePlanSummary(out)
+}
+
+// parsePlanSummary parses the json output of a plan and counts the actions on each
+// resource. A delete and create on same resource, in any order, is a replace.
+func parsePlanSummary(content []byte) (*def.PlanSummary, error) {
+	var p plan
+	if err := json.Unmarshal(content, &p); err != nil {
+		return nil, err
+	}
+
+	summary := &def.PlanSummary{}
+
+	for _, rc := range p.ResourceChanges {
+		actions := map[string]bool{}
+		for _, action := range rc.Change.Actions {
+			actions[action] = true
+		}
+
+		var action string
+
+		switch {
+		case actions["delete"] && actions["create"]:
+			summary.Replace++
+			action = "replace"
+		case actions["create"]:
+			summary.Add++
+			action = "add"
+		case actions["update"]:
+			summary.Change++
+			action = "change"
+		case actions["delete"]:
+			summary.Destroy++
+			action = "destroy"
+		default:
+			continue
+		}
+
+		summary.Resources = append(summary.Resources, &def.ResourceChange{
+			Address: rc.Address,
+			Action:  action,
+		})
+	}
+
+	return summary, nil
+}
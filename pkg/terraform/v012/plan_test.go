@@ -0,0 +1,102 @@
+package v012
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/avinor/tau/pkg/terraform/def"
+)
+
+// resourceChangesJSON returns plan json with a single resource changed with actions
+func resourceChangesJSON(address, actions string) string {
+	return fmt.Sprintf(`{"resource_changes":[{"address":"%s","change":{"actions":%s}}]}`, address, actions)
+}
+
+func TestParsePlanSummary(t *testing.T) {
+	tests := []struct {
+		Content  string
+		Expected *def.PlanSummary
+		Error    bool
+	}{
+		{
+			resourceChangesJSON("azurerm_resource_group.rg", `["create"]`),
+			&def.PlanSummary{Add: 1, Resources: []*def.ResourceChange{{Address: "azurerm_resource_group.rg", Action: "add"}}},
+			false,
+		},
+		{
+			resourceChangesJSON("azurerm_resource_group.rg", `["update"]`),
+			&def.PlanSummary{Change: 1, Resources: []*def.ResourceChange{{Address: "azurerm_resource_group.rg", Action: "change"}}},
+			false,
+		},
+		{
+			resourceChangesJSON("azurerm_resource_group.rg", `["delete"]`),
+			&def.PlanSummary{Destroy: 1, Resources: []*def.ResourceChange{{Address: "azurerm_resource_group.rg", Action: "destroy"}}},
+			false,
+		},
+		{
+			resourceChangesJSON("azurerm_resource_group.rg", `["delete","create"]`),
+			&def.PlanSummary{Replace: 1, Resources: []*def.ResourceChange{{Address: "azurerm_resource_group.rg", Action: "replace"}}},
+			false,
+		},
+		{
+			resourceChangesJSON("azurerm_resource_group.rg", `["create","delete"]`),
+			&def.PlanSummary{Replace: 1, Resources: []*def.ResourceChange{{Address: "azurerm_resource_group.rg", Action: "replace"}}},
+			false,
+		},
+		{
+			resourceChangesJSON("azurerm_resource_group.rg", `["no-op"]`),
+			&def.PlanSummary{},
+			false,
+		},
+		{
+			resourceChangesJSON("data.azurerm_client_config.current", `["read"]`),
+			&def.PlanSummary{},
+			false,
+		},
+		{
+			`{"resource_changes":[
+				{"address":"a.create","change":{"actions":["create"]}},
+				{"address":"a.noop","change":{"actions":["no-op"]}},
+				{"address":"a.replace","change":{"actions":["delete","create"]}},
+				{"address":"a.delete","change":{"actions":["delete"]}}
+			]}`,
+			&def.PlanSummary{Add: 1, Replace: 1, Destroy: 1, Resources: []*def.ResourceChange{
+				{Address: "a.create", Action: "add"},
+				{Address: "a.replace", Action: "replace"},
+				{Address: "a.delete", Action: "destroy"},
+			}},
+			false,
+		},
+		{
+			`{"format_version":"0.1"}`,
+			&def.PlanSummary{},
+			false,
+		},
+		{
+			`{"resource_changes":[`,
+			nil,
+			true,
+		},
+		{
+			`{"resource_changes":{"address":"a"}}`,
+			nil,
+			true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			summary, err := parsePlanSummary([]byte(test.Content))
+
+			if test.Error {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, summary)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package v012
+
+import (
+	"strings"
+
+	"github.com/avinor/tau/pkg/shell"
+)
+
+// SelectWorkspace selects workspace in module. If workspace does not exist yet it will be
+// created, creating a workspace in terraform also selects it.
+func (e *Executor) SelectWorkspace(options *shell.Options, workspace string) error {
+	out, err := shell.Output(options, "terraform", "workspace", "list")
+	if err != nil {
+		return err
+	}
+
+	current, workspaces := parseWorkspaceList(out)
+
+	if current == workspace {
+		return nil
+	}
+
+	for _, ws := range workspaces {
+		if ws == workspace {
+			return e.Execute(options, "workspace", "select", workspace)
+		}
+	}
+
+	return e.Execute(options, "workspace", "new", workspace)
+}
+
+// parseWorkspaceList parses output of `terraform workspace list` and returns the current
+// workspace, marked with *, and all workspaces found
+func parseWorkspaceList(content []byte) (string, []string) {
+	current := ""
+	workspaces := []string{}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+			current = line
+		}
+
+		if line == "" {
+			continue
+		}
+
+		workspaces = append(workspaces, line)
+	}
+
+	return current, workspaces
+}
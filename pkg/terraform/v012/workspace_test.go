@@ -0,0 +1,34 @@
+package v012
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkspaceList(t *testing.T) {
+	tests := []struct {
+		Content    string
+		Current    string
+		Workspaces []string
+	}{
+		{"* default\n", "default", []string{"default"}},
+		{"  default\n* dev\n  prod\n", "dev", []string{"default", "dev", "prod"}},
+		{"* default\n  dev\n\n", "default", []string{"default", "dev"}},
+		{"  default\r\n*   prod  \r\n", "prod", []string{"default", "prod"}},
+		{"  default\n  dev\n", "", []string{"default", "dev"}},
+		{"*default\n", "default", []string{"default"}},
+		{"", "", []string{}},
+		{"\n\n", "", []string{}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			current, workspaces := parseWorkspaceList([]byte(test.Content))
+
+			assert.Equal(t, test.Current, current)
+			assert.Equal(t, test.Workspaces, workspaces)
+		})
+	}
+}